@@ -2,15 +2,35 @@ package main
 
 import (
 	"bytes"
+	"cmp"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"expvar"
 	"fmt"
+	"io"
+	"io/fs"
+	"iter"
 	"log"
+	"maps"
+	"math"
+	"math/rand/v2"
 	"net"
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -19,20 +39,27 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/prometheus/client_golang/prometheus/push"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/common/model"
 	"golang.org/x/oauth2"
 	"golang.org/x/sync/errgroup"
 )
 
 // constants settable at build time
 var (
-	Version = "1.3.1"
+	Version   = "1.3.1"
+	Commit    = ""
+	BuildDate = ""
 )
 
 var (
-	registry = prometheus.NewRegistry()
+	registry = &metricsRegistry{
+		Registry:     prometheus.NewRegistry(),
+		descriptions: make(map[prometheus.Collector]metricDescription),
+	}
 
-	repoCount = prometheus.NewGaugeVec(
+	repoCount = newGaugeVec(
 		prometheus.GaugeOpts{
 			Name: "github_repo_count",
 			Help: "The total number of repositories",
@@ -40,7 +67,31 @@ var (
 		[]string{"owner", "visibility", "archived"},
 	)
 
-	issueCount = prometheus.NewGaugeVec(
+	repoStars = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_repo_stars",
+			Help: "The number of stargazers of a repository",
+		},
+		[]string{"github_repo"},
+	)
+
+	repoForks = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_repo_forks",
+			Help: "The number of forks of a repository",
+		},
+		[]string{"github_repo"},
+	)
+
+	repoDefaultBranchCommitTimestamp = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_repo_default_branch_commit_timestamp_seconds",
+			Help: "The commit time of the latest commit on the default branch",
+		},
+		[]string{"github_repo"},
+	)
+
+	issueCount = newGaugeVec(
 		prometheus.GaugeOpts{
 			Name: "github_issue_count",
 			Help: "The count of issues or pulls",
@@ -48,7 +99,23 @@ var (
 		[]string{"github_repo", "type", "state"},
 	)
 
-	notificationCount = prometheus.NewGaugeVec(
+	issueLabelCount = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_issue_label_count",
+			Help: "The count of open issues with a label",
+		},
+		[]string{"github_repo", "label"},
+	)
+
+	issueOldestOpenAge = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_issue_oldest_open_age_seconds",
+			Help: "The age of the oldest open issue or pull",
+		},
+		[]string{"github_repo", "type"},
+	)
+
+	notificationCount = newGaugeVec(
 		prometheus.GaugeOpts{
 			Name: "github_notification_count",
 			Help: "The number of notifications",
@@ -56,7 +123,30 @@ var (
 		[]string{"unread"},
 	)
 
-	workflowRunNumber = prometheus.NewGaugeVec(
+	notificationReasonCount = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_notification_reason_count",
+			Help: "The number of unread notifications by reason",
+		},
+		[]string{"reason"},
+	)
+
+	notificationOldestUnreadAge = newGauge(
+		prometheus.GaugeOpts{
+			Name: "github_notification_oldest_unread_age_seconds",
+			Help: "The age of the oldest unread notification, or 0 if there are none",
+		},
+	)
+
+	notificationRepoCount = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_notification_repo_count",
+			Help: "The number of unread notifications by repository",
+		},
+		[]string{"github_repo"},
+	)
+
+	workflowRunNumber = newGaugeVec(
 		prometheus.GaugeOpts{
 			Name: "github_workflow_run_number",
 			Help: "The latest run number for a workflow.",
@@ -64,541 +154,6987 @@ var (
 		[]string{"github_repo", "workflow_name"},
 	)
 
-	workflowRunState = prometheus.NewGaugeVec(
+	workflowRunState = newGaugeVec(
 		prometheus.GaugeOpts{
 			Name: "github_workflow_run_conclusion",
 			Help: "The latest state of a workflow run.",
 		},
 		[]string{"github_repo", "workflow_name", "github_workflow_run_conclusion"},
 	)
-)
 
-func init() {
-	registry.MustRegister(repoCount)
-	registry.MustRegister(issueCount)
-	registry.MustRegister(notificationCount)
-	registry.MustRegister(workflowRunNumber)
-	registry.MustRegister(workflowRunState)
-}
+	workflowLastRunTimestamp = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_workflow_last_run_timestamp_seconds",
+			Help: "The start time of the latest completed run of a workflow.",
+		},
+		[]string{"github_repo", "workflow_name"},
+	)
 
-type generateCommand struct {
-	Output             string  `arg:"-o,--output,env:GITHUB_EXPORTER_OUTPUT" placeholder:"FILE"`
-	PushgatewayURL     url.URL `arg:"-p,--pushgateway-url,env:GITHUB_EXPORTER_PUSHGATEWAY_URL" placeholder:"URL"`
-	PushgatewayRetries int     `arg:"-r,--pushgateway-retries,env:GITHUB_EXPORTER_PUSHGATEWAY_RETRIES" default:"1" placeholder:"RETRIES"`
-}
+	staleBranchCount = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_stale_branch_count",
+			Help: "The number of non-default branches whose last commit is older than the stale threshold.",
+		},
+		[]string{"github_repo"},
+	)
 
-type serveCommand struct {
-	Addr     string        `arg:"-l,--listen,env:GITHUB_EXPORTER_LISTEN" default:":9448" placeholder:"ADDRESS:PORT"`
-	Interval time.Duration `arg:"-i,--interval,env:GITHUB_EXPORTER_INTERVAL" default:"15m" placeholder:"INTERVAL"`
-}
+	forkAheadBy = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_fork_commits_ahead",
+			Help: "The number of commits the fork's default branch is ahead of its parent.",
+		},
+		[]string{"github_repo", "parent_repo"},
+	)
 
-type mainCommand struct {
-	Token    string           `arg:"-t,--token,env:GITHUB_TOKEN" placeholder:"TOKEN"`
-	Verbose  bool             `arg:"-v,--verbose,env:GITHUB_EXPORTER_VERBOSE" help:"Enable verbose logging"`
-	Version  bool             `arg:"-V,--version" help:"Print version information"`
-	Generate *generateCommand `arg:"subcommand:generate"`
-	Serve    *serveCommand    `arg:"subcommand:serve"`
-}
+	forkBehindBy = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_fork_commits_behind",
+			Help: "The number of commits the fork's default branch is behind its parent.",
+		},
+		[]string{"github_repo", "parent_repo"},
+	)
 
-func main() {
-	var args mainCommand
-	p := arg.MustParse(&args)
+	branchProtectionEnabled = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_branch_protection_enabled",
+			Help: "Whether the default branch has branch protection enabled.",
+		},
+		[]string{"github_repo", "branch"},
+	)
 
-	if args.Version {
-		fmt.Println(Version)
-		os.Exit(0)
-	}
+	branchProtectionRequiredReviews = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_branch_protection_required_reviews",
+			Help: "The number of approving reviews required to merge into the default branch.",
+		},
+		[]string{"github_repo", "branch"},
+	)
 
-	if args.Token == "" {
-		args.Token = fetchGitHubToken()
-	}
+	branchProtectionRequiredStatusChecks = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_branch_protection_required_status_checks",
+			Help: "The number of status checks required to merge into the default branch.",
+		},
+		[]string{"github_repo", "branch"},
+	)
 
-	if args.Token == "" {
-		p.WriteUsage(os.Stderr)
-		fmt.Fprintln(os.Stderr, "error: --token is required (or environment variable GITHUB_TOKEN)")
-		os.Exit(1)
-	}
+	branchProtectionEnforceAdmins = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_branch_protection_enforce_admins",
+			Help: "Whether branch protection rules are enforced for administrators.",
+		},
+		[]string{"github_repo", "branch"},
+	)
 
-	warnIfIncompatibleToken(args.Token)
+	rulesetCount = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_ruleset_count",
+			Help: "The number of active rulesets applying to a repository by target.",
+		},
+		[]string{"github_repo", "target"},
+	)
 
-	ctx := context.Background()
+	commitStatusState = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_commit_status_state",
+			Help: "The combined commit status of the default branch HEAD.",
+		},
+		[]string{"github_repo", "branch", "state"},
+	)
 
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: args.Token},
+	releaseLatestTimestamp = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_release_latest_timestamp_seconds",
+			Help: "The publish time of the latest release.",
+		},
+		[]string{"github_repo"},
 	)
-	httpClient := oauth2.NewClient(ctx, ts)
-	if args.Verbose {
-		httpClient.Transport = &loggingRoundTripper{wrapped: httpClient.Transport}
-	}
-	client := github.NewClient(httpClient)
 
-	switch {
-	case args.Generate != nil:
-		if err := updateGitHubMetrics(client, ctx); err != nil {
-			log.Fatalf("Error fetching metrics: %v", err)
-		}
+	releaseLatestAgeDays = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_release_latest_age_days",
+			Help: "The number of days since the latest release was published.",
+		},
+		[]string{"github_repo"},
+	)
 
-		// If no output or pushgateway is specified, write to stdout
-		if args.Generate.Output == "" && args.Generate.PushgatewayURL.String() == "" {
-			args.Generate.Output = "-"
-		}
+	releaseLatestInfo = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_release_latest_info",
+			Help: "Information about the latest release.",
+		},
+		[]string{"github_repo", "tag_name"},
+	)
 
-		if args.Generate.Output == "-" {
-			if err := writeToStdout(registry); err != nil {
-				log.Fatalf("Error writing metrics: %v", err)
-			}
-		} else if args.Generate.Output != "" {
-			if err := prometheus.WriteToTextfile(args.Generate.Output, registry); err != nil {
-				log.Fatalf("Error writing metrics: %v", err)
-			}
-		}
+	releaseAssetDownloadCount = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_release_asset_download_count",
+			Help: "The number of times a release asset has been downloaded.",
+		},
+		[]string{"github_repo", "tag_name", "asset_name"},
+	)
 
-		if args.Generate.PushgatewayURL.String() != "" {
-			pushHTTPClient := http.DefaultClient
-			pusher := push.New(args.Generate.PushgatewayURL.String(), "github").Client(pushHTTPClient).Gatherer(registry)
-			var err error
-			for i := 1; i < args.Generate.PushgatewayRetries; i++ {
-				if err = pusher.Push(); err == nil {
-					break
-				}
-				log.Printf("Error pushing metrics, retrying (%d/%d): %v", i, args.Generate.PushgatewayRetries, err)
-				time.Sleep(2 * time.Second)
-			}
-			if err != nil {
-				log.Fatalf("Error pushing metrics after %d retries: %v", args.Generate.PushgatewayRetries, err)
-			}
-		}
+	releaseCount = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_release_count",
+			Help: "The number of releases by type.",
+		},
+		[]string{"github_repo", "type"},
+	)
 
-	case args.Serve != nil:
-		go func() {
-			log.Printf("[%s] Updating GitHub metrics", time.Now().Format(time.RFC3339))
-			if err := updateGitHubMetrics(client, ctx); err != nil {
-				log.Printf("[%s] Error fetching metrics: %v", time.Now().Format(time.RFC3339), err)
-			}
+	unreleasedCommitCount = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_release_unreleased_commits",
+			Help: "The number of commits on the default branch since the latest release.",
+		},
+		[]string{"github_repo"},
+	)
 
-			for range time.Tick(args.Serve.Interval) {
-				log.Printf("[%s] Updating GitHub metrics", time.Now().Format(time.RFC3339))
-				if err := updateGitHubMetrics(client, ctx); err != nil {
-					log.Printf("[%s] Error fetching GitHub metrics: %v", time.Now().Format(time.RFC3339), err)
-				}
-			}
-		}()
+	packageCount = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_package_count",
+			Help: "The number of packages by type.",
+		},
+		[]string{"owner", "package_type"},
+	)
 
-		var ln net.Listener
-		var err error
-		if os.Getenv("LISTEN_FDS") == "1" {
-			ln, err = activationListener()
-		} else {
-			ln, err = net.Listen("tcp", args.Serve.Addr)
-		}
-		if err != nil {
-			log.Fatalf("Error listening on %s: %v", args.Serve.Addr, err)
-		}
-		defer func() {
-			if err := ln.Close(); err != nil {
-				log.Printf("Error closing listener: %v", err)
-			}
-		}()
+	packageVersionCount = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_package_version_count",
+			Help: "The number of versions of a package.",
+		},
+		[]string{"owner", "package_type", "package_name"},
+	)
 
-		http.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry}))
-		log.Fatal(http.Serve(ln, nil))
+	packageDownloadCount = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_package_download_count",
+			Help: "The total downloads of a package, for registries that report them.",
+		},
+		[]string{"owner", "package_type", "package_name"},
+	)
 
-	default:
-		p.WriteHelp(os.Stdout)
-		os.Exit(1)
-	}
-}
+	packageLatestVersionAge = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_package_latest_version_age_seconds",
+			Help: "The age of the most recently created version of a container package.",
+		},
+		[]string{"owner", "package_name"},
+	)
 
-func warnIfIncompatibleToken(token string) {
-	switch {
-	case strings.HasPrefix(token, "github_pat_"):
-		log.Println("Warning: fine-grained personal access tokens do not support the notifications API; notification metrics will fail")
-	case strings.HasPrefix(token, "ghs_"):
-		log.Println("Warning: GitHub App installation tokens do not support user-scoped APIs; notifications, issue, and repository metrics will fail")
-	case strings.HasPrefix(token, "ghu_"):
-		log.Println("Warning: GitHub App user-to-server tokens do not support the notifications API; notification metrics will fail")
-	}
-}
+	packageUntaggedVersionCount = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_package_untagged_version_count",
+			Help: "The number of untagged versions of a container package.",
+		},
+		[]string{"owner", "package_name"},
+	)
 
-type loggingRoundTripper struct {
-	wrapped http.RoundTripper
-}
+	pagesEnabled = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_pages_enabled",
+			Help: "Whether GitHub Pages is enabled for a repository.",
+		},
+		[]string{"github_repo"},
+	)
 
-func (l loggingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
-	fmt.Fprintf(os.Stderr, "[%s] %s %s\n", time.Now().Format(time.RFC3339), req.Method, req.URL)
-	return l.wrapped.RoundTrip(req)
-}
+	pagesBuildStatus = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_pages_build_status",
+			Help: "The status of the latest GitHub Pages build.",
+		},
+		[]string{"github_repo", "status"},
+	)
 
-func fetchGitHubToken() string {
-	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
-		return token
-	}
-	if token := os.Getenv("GH_TOKEN"); token != "" {
-		return token
-	}
+	pagesBuildTimestamp = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_pages_build_timestamp_seconds",
+			Help: "The time the latest GitHub Pages build was last updated.",
+		},
+		[]string{"github_repo"},
+	)
 
-	if credsDir := os.Getenv("CREDENTIALS_DIRECTORY"); credsDir != "" {
-		filenames := []string{"GITHUB_TOKEN", "GH_TOKEN", "github-token", "gh-token"}
-		for _, filename := range filenames {
-			filepath := credsDir + "/" + filename
-			if data, err := os.ReadFile(filepath); err == nil {
-				token := string(bytes.TrimSpace(data))
-				if token != "" {
-					return token
-				}
-			}
-		}
-	}
+	deployKeyCount = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_deploy_key_count",
+			Help: "The number of deploy keys configured for a repository.",
+		},
+		[]string{"github_repo"},
+	)
 
-	return ""
-}
+	deployKeyOldestAge = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_deploy_key_oldest_age_seconds",
+			Help: "The age of the oldest deploy key configured for a repository.",
+		},
+		[]string{"github_repo"},
+	)
 
-func updateGitHubMetrics(client *github.Client, ctx context.Context) error {
-	g, ctx := errgroup.WithContext(ctx)
+	webhookCount = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_webhook_count",
+			Help: "The number of webhooks configured for a repository.",
+		},
+		[]string{"github_repo"},
+	)
 
-	g.Go(func() error {
-		if err := updateNotificationsMetrics(ctx, client); err != nil {
-			return fmt.Errorf("notifications metrics: %w", err)
-		}
-		return nil
-	})
+	webhookFailedDeliveries = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_webhook_failed_deliveries",
+			Help: "The number of failed webhook deliveries within the delivery window.",
+		},
+		[]string{"github_repo", "hook_id"},
+	)
 
-	g.Go(func() error {
-		if err := updateIssueMetrics(ctx, client); err != nil {
-			return fmt.Errorf("issue metrics: %w", err)
-		}
-		return nil
-	})
+	orgWebhookCount = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_org_webhook_count",
+			Help: "The number of active webhooks configured for an organization.",
+		},
+		[]string{"org"},
+	)
+
+	orgWebhookFailedDeliveries = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_org_webhook_failed_deliveries",
+			Help: "The number of failed organization webhook deliveries within the delivery window.",
+		},
+		[]string{"org", "hook_id"},
+	)
+
+	staleIssueCount = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_issue_stale_count",
+			Help: "The number of open issues or pulls with no activity within the stale threshold.",
+		},
+		[]string{"github_repo", "type"},
+	)
+
+	issueAssigneeCount = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_issue_assignee_count",
+			Help: "The number of open issues assigned to a user.",
+		},
+		[]string{"github_repo", "assignee"},
+	)
+
+	issueFirstResponse = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_issue_first_response_seconds",
+			Help: "Quantiles of time until the first non-author comment on recently opened issues.",
+		},
+		[]string{"github_repo", "quantile"},
+	)
+
+	issueOpenedCount = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_issue_opened_count",
+			Help: "The number of issues opened within a recent window.",
+		},
+		[]string{"github_repo", "window"},
+	)
+
+	issueClosedCount = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_issue_closed_count",
+			Help: "The number of issues closed within a recent window.",
+		},
+		[]string{"github_repo", "window"},
+	)
+
+	pullReviewDecisionCount = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_pull_review_decision_count",
+			Help: "The number of open pulls by review decision.",
+		},
+		[]string{"github_repo", "review_decision"},
+	)
+
+	pullDraftCount = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_pull_draft_count",
+			Help: "The number of open draft pulls.",
+		},
+		[]string{"github_repo"},
+	)
+
+	pullTimeToMerge = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_pull_time_to_merge_seconds",
+			Help: "Quantiles of time from open to merge for recently merged pulls.",
+		},
+		[]string{"github_repo", "quantile"},
+	)
+
+	pullSizeLines = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_pull_size_lines",
+			Help: "Quantiles of lines added plus deleted for recently merged pulls.",
+		},
+		[]string{"github_repo", "quantile"},
+	)
+
+	pullChangedFiles = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_pull_changed_files",
+			Help: "Quantiles of changed files for recently merged pulls.",
+		},
+		[]string{"github_repo", "quantile"},
+	)
+
+	pullReviewRequestedCount = newGauge(
+		prometheus.GaugeOpts{
+			Name: "github_pull_review_requested_count",
+			Help: "The number of open pulls requesting review from the user or their teams.",
+		},
+	)
+
+	pullAuthoredOpenCount = newGauge(
+		prometheus.GaugeOpts{
+			Name: "github_pull_authored_open_count",
+			Help: "The number of open pulls authored by the user across GitHub.",
+		},
+	)
+
+	pullAuthoredOpenByOwnerCount = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_pull_authored_open_by_owner_count",
+			Help: "The number of open pulls authored by the user by repository owner.",
+		},
+		[]string{"owner"},
+	)
+
+	issueAssignedOpenCount = newGauge(
+		prometheus.GaugeOpts{
+			Name: "github_issue_assigned_open_count",
+			Help: "The number of open issues assigned to the user across GitHub.",
+		},
+	)
+
+	// A vector without labels, so the series can be removed when nothing is
+	// assigned.
+	issueAssignedOldestAge = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_issue_assigned_oldest_age_seconds",
+			Help: "The age of the oldest open issue assigned to the user across GitHub.",
+		},
+		[]string{},
+	)
+
+	pullBotOpenCount = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_pull_bot_open_count",
+			Help: "The number of open pulls authored by a bot.",
+		},
+		[]string{"github_repo", "author"},
+	)
+
+	pullBotOldestAge = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_pull_bot_oldest_age_seconds",
+			Help: "The age of the oldest open pull authored by a bot.",
+		},
+		[]string{"github_repo", "author"},
+	)
+
+	mergeQueueDepth = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_merge_queue_depth",
+			Help: "The number of pulls in the default branch merge queue.",
+		},
+		[]string{"github_repo", "branch"},
+	)
+
+	mergeQueueOldestAge = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_merge_queue_oldest_age_seconds",
+			Help: "The time the oldest entry has spent in the default branch merge queue.",
+		},
+		[]string{"github_repo", "branch"},
+	)
+
+	milestoneIssueCount = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_milestone_issue_count",
+			Help: "The number of issues in an open milestone by state.",
+		},
+		[]string{"github_repo", "milestone", "state"},
+	)
+
+	milestoneDueTimestamp = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_milestone_due_timestamp_seconds",
+			Help: "The due date of an open milestone.",
+		},
+		[]string{"github_repo", "milestone"},
+	)
+
+	discussionCount = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_discussion_count",
+			Help: "The number of discussions by state.",
+		},
+		[]string{"github_repo", "state"},
+	)
+
+	discussionAnsweredCount = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_discussion_answered_count",
+			Help: "The number of open discussions in question categories by whether they are answered.",
+		},
+		[]string{"github_repo", "answered"},
+	)
+
+	issueThumbsUpCount = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_issue_thumbs_up_count",
+			Help: "The number of thumbs up reactions on one of the most upvoted open issues.",
+		},
+		[]string{"github_repo", "number"},
+	)
+
+	issueCommentCount = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_issue_comment_count",
+			Help: "The number of comments on one of the most upvoted open issues.",
+		},
+		[]string{"github_repo", "number"},
+	)
+
+	dependabotAlertCount = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_dependabot_alert_count",
+			Help: "The number of open Dependabot alerts by severity.",
+		},
+		[]string{"github_repo", "severity"},
+	)
+
+	secretScanningAlertCount = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_secret_scanning_alert_count",
+			Help: "The number of secret scanning alerts by secret type and state.",
+		},
+		[]string{"github_repo", "secret_type", "state"},
+	)
+
+	orgDependabotAlertCount = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_org_dependabot_alert_count",
+			Help: "The number of open Dependabot alerts in an organization by severity.",
+		},
+		[]string{"org", "severity"},
+	)
+
+	orgCodeScanningAlertCount = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_org_code_scanning_alert_count",
+			Help: "The number of open code scanning alerts in an organization by severity.",
+		},
+		[]string{"org", "severity"},
+	)
+
+	orgSecretScanningAlertCount = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_org_secret_scanning_alert_count",
+			Help: "The number of secret scanning alerts in an organization by state.",
+		},
+		[]string{"org", "state"},
+	)
+
+	dependencyCount = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_dependency_count",
+			Help: "The number of dependencies in the dependency graph by ecosystem and relationship.",
+		},
+		[]string{"github_repo", "ecosystem", "relationship"},
+	)
+
+	dependentsCount = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_dependents_count",
+			Help: "The number of repositories or packages that depend on a repository.",
+		},
+		[]string{"github_repo", "type"},
+	)
+
+	securityFeatureEnabled = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_security_feature_enabled",
+			Help: "Whether a security feature is enabled for a repository.",
+		},
+		[]string{"github_repo", "feature"},
+	)
+
+	communityHealthPercentage = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_community_health_percentage",
+			Help: "The community profile health percentage of a repository.",
+		},
+		[]string{"github_repo"},
+	)
+
+	communityFilePresent = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_community_file_present",
+			Help: "Whether a repository has a community health file.",
+		},
+		[]string{"github_repo", "file"},
+	)
+
+	teamMemberCount = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_team_member_count",
+			Help: "The number of members of a team.",
+		},
+		[]string{"org", "team"},
+	)
+
+	teamRepoCount = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_team_repo_count",
+			Help: "The number of repositories a team has access to.",
+		},
+		[]string{"org", "team"},
+	)
+
+	orgPendingInvitationCount = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_org_pending_invitation_count",
+			Help: "The number of pending organization invitations.",
+		},
+		[]string{"org"},
+	)
+
+	orgPendingInvitationOldestAge = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_org_pending_invitation_oldest_age_seconds",
+			Help: "The age of the oldest pending organization invitation.",
+		},
+		[]string{"org"},
+	)
+
+	orgMembersWithout2FA = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_org_members_without_2fa",
+			Help: "The number of organization members with two-factor authentication disabled.",
+		},
+		[]string{"org"},
+	)
+
+	orgOutsideCollaboratorCount = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_org_outside_collaborator_count",
+			Help: "The number of outside collaborators in an organization.",
+		},
+		[]string{"org"},
+	)
+
+	repoOutsideCollaboratorCount = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_repo_outside_collaborator_count",
+			Help: "The number of outside collaborators with access to a repository.",
+		},
+		[]string{"github_repo"},
+	)
+
+	orgAppInstallationCount = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_org_app_installation_count",
+			Help: "The number of GitHub App installations on an organization.",
+		},
+		[]string{"org"},
+	)
+
+	orgAppInstallationInfo = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_org_app_installation_info",
+			Help: "A GitHub App installed on an organization.",
+		},
+		[]string{"org", "app_slug"},
+	)
+
+	orgPlanInfo = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_org_plan_info",
+			Help: "The plan of an organization.",
+		},
+		[]string{"org", "plan"},
+	)
+
+	orgSeats = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_org_seats",
+			Help: "The total number of seats in an organization's plan.",
+		},
+		[]string{"org"},
+	)
+
+	orgFilledSeats = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_org_filled_seats",
+			Help: "The number of filled seats in an organization's plan.",
+		},
+		[]string{"org"},
+	)
+
+	enterpriseSeatsPurchased = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_enterprise_seats_purchased",
+			Help: "The number of licensed seats purchased by an enterprise.",
+		},
+		[]string{"enterprise"},
+	)
+
+	enterpriseSeatsConsumed = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_enterprise_seats_consumed",
+			Help: "The number of licensed seats consumed in an enterprise.",
+		},
+		[]string{"enterprise"},
+	)
+
+	copilotSeatCount = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_copilot_seat_count",
+			Help: "The number of Copilot seats in an organization by state.",
+		},
+		[]string{"org", "state"},
+	)
+
+	billingActionsMinutesUsed = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_billing_actions_minutes_used",
+			Help: "The Actions minutes used in the current billing cycle.",
+		},
+		[]string{"owner"},
+	)
+
+	billingActionsMinutesIncluded = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_billing_actions_minutes_included",
+			Help: "The Actions minutes included in the plan.",
+		},
+		[]string{"owner"},
+	)
+
+	billingActionsPaidMinutesUsed = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_billing_actions_paid_minutes_used",
+			Help: "The paid Actions minutes used in the current billing cycle.",
+		},
+		[]string{"owner"},
+	)
+
+	billingPackagesBandwidthUsed = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_billing_packages_bandwidth_used_gigabytes",
+			Help: "The Packages data transfer used in the current billing cycle.",
+		},
+		[]string{"owner"},
+	)
+
+	billingPackagesBandwidthIncluded = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_billing_packages_bandwidth_included_gigabytes",
+			Help: "The Packages data transfer included in the plan.",
+		},
+		[]string{"owner"},
+	)
+
+	billingSharedStorageEstimated = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_billing_shared_storage_estimated_gigabytes",
+			Help: "The estimated Actions and Packages shared storage for the month.",
+		},
+		[]string{"owner"},
+	)
+
+	billingSharedStorageEstimatedPaid = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_billing_shared_storage_estimated_paid_gigabytes",
+			Help: "The estimated paid Actions and Packages shared storage for the month.",
+		},
+		[]string{"owner"},
+	)
+
+	billingDaysLeftInCycle = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_billing_days_left_in_cycle",
+			Help: "The number of days left in the billing cycle.",
+		},
+		[]string{"owner"},
+	)
+
+	gistCount = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_gist_count",
+			Help: "The number of gists by visibility.",
+		},
+		[]string{"visibility"},
+	)
+
+	gistLastUpdatedTimestamp = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_gist_last_updated_timestamp_seconds",
+			Help: "The time the most recently updated gist was updated.",
+		},
+		[]string{"visibility"},
+	)
+
+	sponsorCount = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_sponsors_count",
+			Help: "The number of current sponsors.",
+		},
+		[]string{"owner"},
+	)
+
+	sponsorMonthlyIncome = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_sponsors_monthly_income_dollars",
+			Help: "The estimated monthly income from GitHub Sponsors.",
+		},
+		[]string{"owner"},
+	)
+
+	userFollowers = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_user_followers",
+			Help: "The number of followers of a user.",
+		},
+		[]string{"user"},
+	)
+
+	userFollowing = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_user_following",
+			Help: "The number of users a user follows.",
+		},
+		[]string{"user"},
+	)
+
+	starredRepoCount = newGauge(
+		prometheus.GaugeOpts{
+			Name: "github_starred_repo_count",
+			Help: "The number of repositories starred by the user.",
+		},
+	)
+
+	watchedRepoCount = newGauge(
+		prometheus.GaugeOpts{
+			Name: "github_watched_repo_count",
+			Help: "The number of repositories watched by the user.",
+		},
+	)
+
+	statusComponentStatus = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_status_component_status",
+			Help: "The current status of a githubstatus.com component.",
+		},
+		[]string{"component", "status"},
+	)
+
+	repoEventCount = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_repo_event_count",
+			Help: "The number of recent repository events by type.",
+		},
+		[]string{"github_repo", "type", "window"},
+	)
+
+	collectorSkipped = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_exporter_collector_skipped",
+			Help: "Whether an enabled collector has been skipped, and why.",
+		},
+		[]string{"collector", "reason"},
+	)
+
+	tokenScope = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_token_scope",
+			Help: "The OAuth scopes granted to the token.",
+		},
+		[]string{"scope"},
+	)
+
+	tokenExpiration = newGauge(
+		prometheus.GaugeOpts{
+			Name: "github_token_expiration_timestamp_seconds",
+			Help: "The time the token expires, for tokens with an expiration.",
+		},
+	)
+
+	doraDeploymentsPerDay = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_dora_deployments_per_day",
+			Help: "Successful deployments per day over the DORA window.",
+		},
+		[]string{"github_repo"},
+	)
+
+	doraLeadTime = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_dora_lead_time_seconds",
+			Help: "Quantiles of time from pull merge to the next successful deployment.",
+		},
+		[]string{"github_repo", "quantile"},
+	)
+
+	doraChangeFailureRate = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_dora_change_failure_rate",
+			Help: "The fraction of finished deployments that failed over the DORA window.",
+		},
+		[]string{"github_repo"},
+	)
+
+	doraTimeToRestore = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_dora_time_to_restore_seconds",
+			Help: "Quantiles of time from a failed deployment to the next successful one.",
+		},
+		[]string{"github_repo", "quantile"},
+	)
+
+	environmentRequiredReviewers = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_environment_required_reviewers",
+			Help: "The number of required reviewers for deployments to an environment.",
+		},
+		[]string{"github_repo", "environment"},
+	)
+
+	environmentWaitTimerMinutes = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_environment_wait_timer_minutes",
+			Help: "The wait timer before deployments to an environment proceed.",
+		},
+		[]string{"github_repo", "environment"},
+	)
+
+	environmentInfo = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_environment_info",
+			Help: "Information about an environment's deployment branch policy and admin bypass.",
+		},
+		[]string{"github_repo", "environment", "branch_policy", "admins_bypass"},
+	)
+
+	checkRunCount = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_check_run_count",
+			Help: "The number of check runs on the default branch head by conclusion.",
+		},
+		[]string{"github_repo", "conclusion"},
+	)
+
+	checkRunSlowestDuration = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_check_run_slowest_duration_seconds",
+			Help: "The duration of the slowest completed check run on the default branch head.",
+		},
+		[]string{"github_repo", "check_name"},
+	)
+
+	effectiveInterval = newGauge(
+		prometheus.GaugeOpts{
+			Name: "github_exporter_interval_seconds",
+			Help: "The effective collection interval in serve mode.",
+		},
+	)
+
+	scrapesInFlight = newGauge(
+		prometheus.GaugeOpts{
+			Name: "github_exporter_scrapes_in_flight",
+			Help: "The number of /metrics requests currently being served.",
+		},
+	)
+
+	scrapeDuration = newHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "github_exporter_scrape_duration_seconds",
+			Help:    "The time taken to serve /metrics requests.",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"code", "method"},
+	)
+
+	seriesDropped = newCounterVec(
+		prometheus.CounterOpts{
+			Name: "github_exporter_series_dropped_total",
+			Help: "The number of series dropped for exceeding --series-limit.",
+		},
+		[]string{"metric"},
+	)
+
+	repoDirectCollaboratorCount = newGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "github_repo_direct_collaborator_count",
+			Help: "The number of collaborators added directly to a repository, rather than through an organization or team.",
+		},
+		[]string{"github_repo"},
+	)
+)
+
+func init() {
+	registry.MustRegister(repoCount)
+	registry.MustRegister(repoStars)
+	registry.MustRegister(repoForks)
+	registry.MustRegister(repoDefaultBranchCommitTimestamp)
+	registry.MustRegister(issueCount)
+	registry.MustRegister(issueLabelCount)
+	registry.MustRegister(issueOldestOpenAge)
+	registry.MustRegister(notificationCount)
+	registry.MustRegister(notificationReasonCount)
+	registry.MustRegister(notificationRepoCount)
+	registry.MustRegister(notificationOldestUnreadAge)
+	registry.MustRegister(workflowRunNumber)
+	registry.MustRegister(workflowRunState)
+	registry.MustRegister(workflowLastRunTimestamp)
+	registry.MustRegister(staleBranchCount)
+	registry.MustRegister(forkAheadBy)
+	registry.MustRegister(forkBehindBy)
+	registry.MustRegister(branchProtectionEnabled)
+	registry.MustRegister(branchProtectionRequiredReviews)
+	registry.MustRegister(branchProtectionRequiredStatusChecks)
+	registry.MustRegister(branchProtectionEnforceAdmins)
+	registry.MustRegister(rulesetCount)
+	registry.MustRegister(commitStatusState)
+	registry.MustRegister(releaseLatestTimestamp)
+	registry.MustRegister(releaseLatestAgeDays)
+	registry.MustRegister(releaseLatestInfo)
+	registry.MustRegister(releaseAssetDownloadCount)
+	registry.MustRegister(releaseCount)
+	registry.MustRegister(unreleasedCommitCount)
+	registry.MustRegister(packageCount)
+	registry.MustRegister(packageVersionCount)
+	registry.MustRegister(packageDownloadCount)
+	registry.MustRegister(packageLatestVersionAge)
+	registry.MustRegister(packageUntaggedVersionCount)
+	registry.MustRegister(pagesEnabled)
+	registry.MustRegister(pagesBuildStatus)
+	registry.MustRegister(pagesBuildTimestamp)
+	registry.MustRegister(deployKeyCount)
+	registry.MustRegister(deployKeyOldestAge)
+	registry.MustRegister(webhookCount)
+	registry.MustRegister(webhookFailedDeliveries)
+	registry.MustRegister(orgWebhookCount)
+	registry.MustRegister(orgWebhookFailedDeliveries)
+	registry.MustRegister(staleIssueCount)
+	registry.MustRegister(issueAssigneeCount)
+	registry.MustRegister(issueFirstResponse)
+	registry.MustRegister(issueOpenedCount)
+	registry.MustRegister(issueClosedCount)
+	registry.MustRegister(pullReviewDecisionCount)
+	registry.MustRegister(pullDraftCount)
+	registry.MustRegister(pullTimeToMerge)
+	registry.MustRegister(pullSizeLines)
+	registry.MustRegister(pullChangedFiles)
+	registry.MustRegister(pullReviewRequestedCount)
+	registry.MustRegister(pullAuthoredOpenCount)
+	registry.MustRegister(pullAuthoredOpenByOwnerCount)
+	registry.MustRegister(issueAssignedOpenCount)
+	registry.MustRegister(issueAssignedOldestAge)
+	registry.MustRegister(pullBotOpenCount)
+	registry.MustRegister(pullBotOldestAge)
+	registry.MustRegister(mergeQueueDepth)
+	registry.MustRegister(mergeQueueOldestAge)
+	registry.MustRegister(milestoneIssueCount)
+	registry.MustRegister(milestoneDueTimestamp)
+	registry.MustRegister(discussionCount)
+	registry.MustRegister(discussionAnsweredCount)
+	registry.MustRegister(issueThumbsUpCount)
+	registry.MustRegister(issueCommentCount)
+	registry.MustRegister(dependabotAlertCount)
+	registry.MustRegister(secretScanningAlertCount)
+	registry.MustRegister(orgDependabotAlertCount)
+	registry.MustRegister(orgCodeScanningAlertCount)
+	registry.MustRegister(orgSecretScanningAlertCount)
+	registry.MustRegister(dependencyCount)
+	registry.MustRegister(dependentsCount)
+	registry.MustRegister(securityFeatureEnabled)
+	registry.MustRegister(communityHealthPercentage)
+	registry.MustRegister(communityFilePresent)
+	registry.MustRegister(teamMemberCount)
+	registry.MustRegister(teamRepoCount)
+	registry.MustRegister(orgPendingInvitationCount)
+	registry.MustRegister(orgPendingInvitationOldestAge)
+	registry.MustRegister(orgMembersWithout2FA)
+	registry.MustRegister(orgOutsideCollaboratorCount)
+	registry.MustRegister(repoOutsideCollaboratorCount)
+	registry.MustRegister(orgAppInstallationCount)
+	registry.MustRegister(orgAppInstallationInfo)
+	registry.MustRegister(orgPlanInfo)
+	registry.MustRegister(orgSeats)
+	registry.MustRegister(orgFilledSeats)
+	registry.MustRegister(enterpriseSeatsPurchased)
+	registry.MustRegister(enterpriseSeatsConsumed)
+	registry.MustRegister(copilotSeatCount)
+	registry.MustRegister(billingActionsMinutesUsed)
+	registry.MustRegister(billingActionsMinutesIncluded)
+	registry.MustRegister(billingActionsPaidMinutesUsed)
+	registry.MustRegister(billingPackagesBandwidthUsed)
+	registry.MustRegister(billingPackagesBandwidthIncluded)
+	registry.MustRegister(billingSharedStorageEstimated)
+	registry.MustRegister(billingSharedStorageEstimatedPaid)
+	registry.MustRegister(billingDaysLeftInCycle)
+	registry.MustRegister(gistCount)
+	registry.MustRegister(gistLastUpdatedTimestamp)
+	registry.MustRegister(sponsorCount)
+	registry.MustRegister(sponsorMonthlyIncome)
+	registry.MustRegister(userFollowers)
+	registry.MustRegister(userFollowing)
+	registry.MustRegister(starredRepoCount)
+	registry.MustRegister(watchedRepoCount)
+	registry.MustRegister(statusComponentStatus)
+	registry.MustRegister(repoEventCount)
+	registry.MustRegister(collectorSkipped)
+	registry.MustRegister(tokenScope)
+	registry.MustRegister(tokenExpiration)
+	registry.MustRegister(doraDeploymentsPerDay)
+	registry.MustRegister(doraLeadTime)
+	registry.MustRegister(doraChangeFailureRate)
+	registry.MustRegister(doraTimeToRestore)
+	registry.MustRegister(environmentRequiredReviewers)
+	registry.MustRegister(environmentWaitTimerMinutes)
+	registry.MustRegister(environmentInfo)
+	registry.MustRegister(checkRunCount)
+	registry.MustRegister(checkRunSlowestDuration)
+	registry.MustRegister(effectiveInterval)
+	registry.MustRegister(scrapesInFlight)
+	registry.MustRegister(scrapeDuration)
+	registry.MustRegister(seriesDropped)
+	registry.MustRegister(repoDirectCollaboratorCount)
+}
+
+type collectorOptions struct {
+	Collectors             []string                `arg:"-c,--collector,separate,env:GITHUB_EXPORTER_COLLECTORS" placeholder:"NAME" help:"Enable an optional collector (may be repeated)"`
+	Repository             string                  `arg:"--repository,env:GITHUB_EXPORTER_REPOSITORY" placeholder:"OWNER/REPO" help:"Only collect metrics for this repository, e.g. from a GitHub Actions workflow"`
+	Orgs                   []string                `arg:"--org,separate,env:GITHUB_EXPORTER_ORGS" placeholder:"ORG" help:"Organization to collect account metrics for (may be repeated)"`
+	Enterprises            []string                `arg:"--enterprise,separate,env:GITHUB_EXPORTER_ENTERPRISES" placeholder:"ENTERPRISE" help:"Enterprise to collect account metrics for (may be repeated)"`
+	Users                  []string                `arg:"--user,separate,env:GITHUB_EXPORTER_USERS" placeholder:"LOGIN" help:"Additional user to collect follower metrics for (may be repeated)"`
+	UpstreamRepos          []string                `arg:"--upstream-repo,separate,env:GITHUB_EXPORTER_UPSTREAM_REPOS" placeholder:"OWNER/REPO" help:"Upstream repository to export latest release metrics for (may be repeated)"`
+	IssueLabels            []string                `arg:"--issue-label,separate,env:GITHUB_EXPORTER_ISSUE_LABELS" placeholder:"LABEL" help:"Export open issue counts for a label (may be repeated)"`
+	StaleBranchAge         time.Duration           `arg:"--stale-branch-age,env:GITHUB_EXPORTER_STALE_BRANCH_AGE" default:"2160h" placeholder:"DURATION" help:"Age after which a branch is considered stale"`
+	AssigneeLimit          int                     `arg:"--assignee-limit,env:GITHUB_EXPORTER_ASSIGNEE_LIMIT" default:"10" placeholder:"N" help:"Maximum assignees per repository before grouping the rest as other"`
+	ResponseWindow         time.Duration           `arg:"--response-window,env:GITHUB_EXPORTER_RESPONSE_WINDOW" default:"720h" placeholder:"DURATION" help:"Window of recently opened issues to measure first response time over"`
+	MergeWindow            time.Duration           `arg:"--merge-window,env:GITHUB_EXPORTER_MERGE_WINDOW" default:"720h" placeholder:"DURATION" help:"Window of recently merged pulls to measure"`
+	AuthoredPullsByOwner   bool                    `arg:"--authored-pulls-by-owner,env:GITHUB_EXPORTER_AUTHORED_PULLS_BY_OWNER" help:"Break down authored open pulls by repository owner"`
+	BotAuthors             []string                `arg:"--bot-author,separate,env:GITHUB_EXPORTER_BOT_AUTHORS" placeholder:"LOGIN" help:"Bot whose open pulls are tracked (may be repeated) [default: dependabot[bot]]"`
+	TopIssues              int                     `arg:"--top-issues,env:GITHUB_EXPORTER_TOP_ISSUES" default:"5" placeholder:"N" help:"Number of most upvoted open issues per repository to export reactions for"`
+	StaleIssueAge          time.Duration           `arg:"--stale-issue-age,env:GITHUB_EXPORTER_STALE_ISSUE_AGE" default:"720h" placeholder:"DURATION" help:"Inactivity after which an open issue or pull is considered stale"`
+	ReleaseAssetReleases   int                     `arg:"--release-asset-releases,env:GITHUB_EXPORTER_RELEASE_ASSET_RELEASES" default:"5" placeholder:"N" help:"Number of most recent releases to export asset download counts for"`
+	ReleaseAssetPattern    *regexp.Regexp          `arg:"--release-asset-pattern,env:GITHUB_EXPORTER_RELEASE_ASSET_PATTERN" placeholder:"REGEX" help:"Only export download counts for assets whose name matches"`
+	NotificationRepoLimit  int                     `arg:"--notification-repo-limit,env:GITHUB_EXPORTER_NOTIFICATION_REPO_LIMIT" placeholder:"N" help:"Break down unread notifications by repository, grouping all but the top N as other"`
+	ReposPerCycle          int                     `arg:"--repos-per-cycle,env:GITHUB_EXPORTER_REPOS_PER_CYCLE" placeholder:"N" help:"Only refresh per-repository metrics for N repositories each cycle, rotating through all of them"`
+	DORAEnvironment        string                  `arg:"--dora-environment,env:GITHUB_EXPORTER_DORA_ENVIRONMENT" default:"production" placeholder:"ENVIRONMENT" help:"Deployment environment to compute DORA metrics for"`
+	DORAWindow             time.Duration           `arg:"--dora-window,env:GITHUB_EXPORTER_DORA_WINDOW" default:"720h" placeholder:"DURATION" help:"Window of recent deployments to compute DORA metrics over"`
+	PerPage                int                     `arg:"--per-page,env:GITHUB_EXPORTER_PER_PAGE" default:"100" placeholder:"N" help:"Page size for paginated REST and GraphQL listing calls, up to 100"`
+	WorkflowCacheTTL       time.Duration           `arg:"--workflow-cache-ttl,env:GITHUB_EXPORTER_WORKFLOW_CACHE_TTL" default:"1h" placeholder:"DURATION" help:"How long to cache each repository's workflow definitions"`
+	RepoListTTL            time.Duration           `arg:"--repo-list-ttl,env:GITHUB_EXPORTER_REPO_LIST_TTL" default:"1h" placeholder:"DURATION" help:"How long to cache the repository list between refreshes"`
+	RateLimitReserve       int                     `arg:"--rate-limit-reserve,env:GITHUB_EXPORTER_RATE_LIMIT_RESERVE" default:"500" placeholder:"N" help:"Defer low-priority collectors for a cycle when fewer than N core API calls remain"`
+	Overrides              map[string]repoOverride `arg:"-"`
+	WorkflowConclusions    []string                `arg:"--workflow-conclusion,separate,env:GITHUB_EXPORTER_WORKFLOW_CONCLUSIONS" placeholder:"CONCLUSION" help:"Workflow run conclusion to export a series for (may be repeated) [default: all]"`
+	WorkflowConclusionMode string                  `arg:"--workflow-conclusion-mode,env:GITHUB_EXPORTER_WORKFLOW_CONCLUSION_MODE" default:"all" placeholder:"MODE" help:"Export every conclusion as 0 or 1 (all), or only the latest run's conclusion (match)"`
+	SeriesLimit            int                     `arg:"--series-limit,env:GITHUB_EXPORTER_SERIES_LIMIT" default:"10000" placeholder:"N" help:"Maximum series per metric, dropping the rest with a warning, or 0 for no limit"`
+	WebhookDeliveryWindow  time.Duration           `arg:"--webhook-delivery-window,env:GITHUB_EXPORTER_WEBHOOK_DELIVERY_WINDOW" default:"24h" placeholder:"DURATION" help:"Window of recent webhook deliveries to count failures in"`
+}
+
+func (o *collectorOptions) enabled(name string) bool {
+	return slices.Contains(o.Collectors, name)
+}
+
+// config is the file given with --config.
+type config struct {
+	// Repositories maps OWNER/REPO to settings for that repository only.
+	Repositories map[string]repoOverride `json:"repositories"`
+	// Relabel rules are applied in order to every exposed series.
+	Relabel []relabelRule `json:"relabel"`
+}
+
+type relabelRule struct {
+	// Metric limits the rule to one metric, or all metrics if empty.
+	Metric string `json:"metric"`
+	// Action is drop to remove Label, rename to rename it to Target, or
+	// replace to change its Value, or any value if empty, to Replacement.
+	Action      string `json:"action"`
+	Label       string `json:"label"`
+	Target      string `json:"target"`
+	Value       string `json:"value"`
+	Replacement string `json:"replacement"`
+}
+
+type repoOverride struct {
+	// Branch is monitored instead of the default branch.
+	Branch string `json:"branch"`
+	// Workflows limits workflow metrics to these workflow names.
+	Workflows []string `json:"workflows"`
+	// DisableCollectors are not run for the repository. "workflows" disables
+	// the default workflow metrics.
+	DisableCollectors []string `json:"disable_collectors"`
+}
+
+func loadConfig(path string) (*config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg config
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	for repo, override := range cfg.Repositories {
+		for _, name := range override.DisableCollectors {
+			if name != "workflows" && !knownCollector(name) {
+				return nil, fmt.Errorf("%s: unknown collector %q for %s", path, name, repo)
+			}
+		}
+	}
+	for i, rule := range cfg.Relabel {
+		switch {
+		case rule.Label == "":
+			return nil, fmt.Errorf("%s: relabel rule %d has no label", path, i+1)
+		case rule.Action == "rename" && rule.Target == "":
+			return nil, fmt.Errorf("%s: relabel rule %d renames %s without a target", path, i+1, rule.Label)
+		// Names are held to the classic [a-zA-Z_][a-zA-Z0-9_]* rules, which
+		// every scraper accepts, so a typo fails here rather than at scrape time.
+		case !model.LegacyValidation.IsValidLabelName(rule.Label):
+			return nil, fmt.Errorf("%s: relabel rule %d has invalid label name %q", path, i+1, rule.Label)
+		case rule.Target != "" && !model.LegacyValidation.IsValidLabelName(rule.Target):
+			return nil, fmt.Errorf("%s: relabel rule %d has invalid target label name %q", path, i+1, rule.Target)
+		case rule.Action != "drop" && rule.Action != "rename" && rule.Action != "replace":
+			return nil, fmt.Errorf("%s: relabel rule %d has unsupported action %q", path, i+1, rule.Action)
+		}
+	}
+	return &cfg, nil
+}
+
+// relabelingGatherer applies relabel rules to the series it gathers. Series
+// that become identical are merged, keeping the first.
+type relabelingGatherer struct {
+	prometheus.Gatherer
+	rules []relabelRule
+}
+
+func (g relabelingGatherer) Gather() ([]*dto.MetricFamily, error) {
+	families, err := g.Gatherer.Gather()
+	for _, mf := range families {
+		seen := make(map[string]bool)
+		metrics := mf.Metric[:0]
+		for _, m := range mf.Metric {
+			m.Label = relabel(mf.GetName(), m.GetLabel(), g.rules)
+
+			key := labelKey(m.Label)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			metrics = append(metrics, m)
+		}
+		mf.Metric = metrics
+	}
+	return families, err
+}
+
+func labelKey(labels []*dto.LabelPair) string {
+	var key strings.Builder
+	for _, lp := range labels {
+		fmt.Fprintf(&key, "%s=%q,", lp.GetName(), lp.GetValue())
+	}
+	return key.String()
+}
+
+// seriesLimitingGatherer exposes at most limit series per metric, in label
+// order, so a label-heavy collector cannot overwhelm Prometheus. Each distinct
+// series dropped is counted and warned about once.
+type seriesLimitingGatherer struct {
+	prometheus.Gatherer
+	limit int
+
+	mu      sync.Mutex
+	dropped map[string]bool
+}
+
+func (g *seriesLimitingGatherer) Gather() ([]*dto.MetricFamily, error) {
+	families, err := g.Gatherer.Gather()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, mf := range families {
+		// The dropped series counter itself has a series per limited metric.
+		if len(mf.Metric) <= g.limit || mf.GetName() == "github_exporter_series_dropped_total" {
+			continue
+		}
+
+		newlyDropped := 0
+		for _, m := range mf.Metric[g.limit:] {
+			key := mf.GetName() + "{" + labelKey(m.GetLabel()) + "}"
+			if g.dropped[key] {
+				continue
+			}
+			g.dropped[key] = true
+			newlyDropped++
+		}
+		if newlyDropped > 0 {
+			log.Printf("Warning: %s has %d series, more than --series-limit %d; dropped %d new series", mf.GetName(), len(mf.Metric), g.limit, newlyDropped)
+			seriesDropped.WithLabelValues(mf.GetName()).Add(float64(newlyDropped))
+		}
+		mf.Metric = mf.Metric[:g.limit]
+	}
+	return families, err
+}
+
+// relabel returns a copy of labels with rules applied, leaving the gathered
+// label pairs, which the registry shares between scrapes, untouched.
+func relabel(metric string, labels []*dto.LabelPair, rules []relabelRule) []*dto.LabelPair {
+	values := make(map[string]string, len(labels))
+	for _, lp := range labels {
+		values[lp.GetName()] = lp.GetValue()
+	}
+
+	for _, rule := range rules {
+		value, ok := values[rule.Label]
+		if !ok || (rule.Metric != "" && rule.Metric != metric) {
+			continue
+		}
+		switch rule.Action {
+		case "drop":
+			delete(values, rule.Label)
+		case "rename":
+			delete(values, rule.Label)
+			values[rule.Target] = value
+		case "replace":
+			if rule.Value == "" || rule.Value == value {
+				values[rule.Label] = rule.Replacement
+			}
+		}
+	}
+
+	relabeled := make([]*dto.LabelPair, 0, len(values))
+	for _, name := range slices.Sorted(maps.Keys(values)) {
+		value := values[name]
+		relabeled = append(relabeled, &dto.LabelPair{Name: &name, Value: &value})
+	}
+	return relabeled
+}
+
+// collectorDisabled reports whether the configuration turns off a collector for
+// one repository.
+func (o *collectorOptions) collectorDisabled(name string, repo *github.Repository) bool {
+	return slices.Contains(o.Overrides[repo.GetFullName()].DisableCollectors, name)
+}
+
+// applyOverride returns repo with its configured branch, if any, in place of
+// the default branch so that every collector monitors it.
+func (o *collectorOptions) applyOverride(repo *github.Repository) *github.Repository {
+	branch := o.Overrides[repo.GetFullName()].Branch
+	if branch == "" {
+		return repo
+	}
+	overridden := *repo
+	overridden.DefaultBranch = &branch
+	return &overridden
+}
+
+// repositoryQueryCollectors are optional collectors served by the shared
+// repository GraphQL query rather than their own API calls.
+var repositoryQueryCollectors = []string{"releases", "branch_protection"}
+
+// lowPriorityCollectors are deferred for a cycle when the remaining rate limit
+// drops below --rate-limit-reserve, so workflow and issue metrics keep
+// refreshing instead of the cycle failing partway through.
+var lowPriorityCollectors = []string{
+	"packages",
+	"container_versions",
+	"dependencies",
+	"dependents",
+	"release_assets",
+	"events",
+	"billing",
+	"copilot",
+}
+
+// deferredCollectors returns the enabled low-priority collectors to skip this
+// cycle given the remaining core rate limit.
+func deferredCollectors(ctx context.Context, client *githubClient, opts *collectorOptions) map[string]bool {
+	var enabled []string
+	for _, name := range lowPriorityCollectors {
+		if opts.enabled(name) {
+			enabled = append(enabled, name)
+		}
+	}
+	if len(enabled) == 0 || opts.RateLimitReserve <= 0 {
+		return nil
+	}
+
+	limits, _, err := client.RateLimit.Get(ctx)
+	if err != nil {
+		log.Printf("Failed to check rate limit, running all collectors: %v", err)
+		return nil
+	}
+	remaining := limits.GetCore().Remaining
+
+	deferred := make(map[string]bool)
+	for _, name := range enabled {
+		value := 0.0
+		if remaining < opts.RateLimitReserve {
+			deferred[name] = true
+			value = 1
+		}
+		collectorSkipped.With(prometheus.Labels{"collector": name, "reason": "rate_limit"}).Set(value)
+	}
+	if len(deferred) > 0 {
+		log.Printf("Only %d core API calls remain, deferring %s", remaining, strings.Join(slices.Sorted(maps.Keys(deferred)), ", "))
+	}
+	return deferred
+}
+
+// skipRetryInterval is how long a collector skipped after a permission error
+// waits before it is tried again, in case the token or repository changed.
+const skipRetryInterval = time.Hour
+
+// skippedCollectors records when collectors failed with a permission error,
+// keyed by collector and repository. The repository is empty for account
+// collectors and for a token missing a scope, which skip the collector
+// everywhere.
+var skippedCollectors = struct {
+	sync.Mutex
+	since map[skipKey]time.Time
+}{since: make(map[skipKey]time.Time)}
+
+type skipKey struct {
+	collector string
+	repo      string
+}
+
+// collectorIsSkipped reports whether a collector is skipped for repo, or for
+// every repository if repo is empty.
+func collectorIsSkipped(name, repo string) bool {
+	skippedCollectors.Lock()
+	defer skippedCollectors.Unlock()
+
+	for _, key := range []skipKey{{name, ""}, {name, repo}} {
+		since, ok := skippedCollectors.since[key]
+		if !ok {
+			continue
+		}
+		if time.Since(since) < skipRetryInterval {
+			return true
+		}
+		delete(skippedCollectors.since, key)
+		if key.repo == "" {
+			collectorSkipped.With(prometheus.Labels{"collector": name, "reason": "permission"}).Set(0)
+		}
+	}
+	return false
+}
+
+// skipCollector skips a collector for skipRetryInterval after a permission
+// error, for just repo unless the token lacks a scope the endpoint needs.
+func skipCollector(name, repo string, err error) {
+	if missingScope(err) {
+		repo = ""
+	}
+
+	skippedCollectors.Lock()
+	defer skippedCollectors.Unlock()
+	key := skipKey{name, repo}
+	if _, ok := skippedCollectors.since[key]; ok {
+		return
+	}
+	skippedCollectors.since[key] = time.Now()
+
+	if repo == "" {
+		log.Printf("Skipping %s collector for %s, the token lacks permission: %v", name, skipRetryInterval, err)
+		collectorSkipped.With(prometheus.Labels{"collector": name, "reason": "permission"}).Set(1)
+	} else {
+		log.Printf("Skipping %s collector for %s for %s, the token lacks permission: %v", name, repo, skipRetryInterval, err)
+	}
+}
+
+// permissionError reports whether err is a 403 or 404 from the GitHub API,
+// which for an optional collector means the token lacks a required scope or
+// the feature is unavailable for the repository. Rate limit errors are
+// distinct types and are not matched.
+func permissionError(err error) bool {
+	var errResp *github.ErrorResponse
+	if !errors.As(err, &errResp) || errResp.Response == nil {
+		return false
+	}
+	return errResp.Response.StatusCode == http.StatusForbidden || errResp.Response.StatusCode == http.StatusNotFound
+}
+
+// missingScope reports whether a permission error is because a classic token
+// has none of the scopes the endpoint accepts, which fails the same way for
+// every repository. Fine-grained and app tokens do not report scopes.
+func missingScope(err error) bool {
+	var errResp *github.ErrorResponse
+	if !errors.As(err, &errResp) || errResp.Response == nil {
+		return false
+	}
+	granted, reported := tokenScopes(&github.Response{Response: errResp.Response})
+	if !reported {
+		return false
+	}
+
+	var accepted []string
+	for _, scope := range strings.Split(errResp.Response.Header.Get("X-Accepted-OAuth-Scopes"), ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			accepted = append(accepted, scope)
+		}
+	}
+	return len(accepted) > 0 && !slices.ContainsFunc(accepted, func(scope string) bool { return hasScope(granted, scope) })
+}
+
+func knownCollector(name string) bool {
+	return slices.Contains(repositoryQueryCollectors, name) ||
+		slices.ContainsFunc(repoCollectors, func(c repoCollector) bool { return c.name == name }) ||
+		slices.ContainsFunc(accountCollectors, func(c accountCollector) bool { return c.name == name })
+}
+
+type repoCollector struct {
+	name   string
+	update func(ctx context.Context, client *githubClient, opts *collectorOptions, repo *github.Repository) error
+}
+
+// repoCollectors are optional per-repository collectors, enabled with --collector.
+var repoCollectors = []repoCollector{
+	{name: "stale_branches", update: updateStaleBranchMetrics},
+	{name: "fork_sync", update: updateForkSyncMetrics},
+	{name: "rulesets", update: updateRulesetMetrics},
+	{name: "commit_status", update: updateCommitStatusMetrics},
+	{name: "release_assets", update: updateReleaseAssetMetrics},
+	{name: "release_counts", update: updateReleaseCountMetrics},
+	{name: "unreleased_commits", update: updateUnreleasedCommitMetrics},
+	{name: "pages", update: updatePagesMetrics},
+	{name: "deploy_keys", update: updateDeployKeyMetrics},
+	{name: "webhooks", update: updateWebhookMetrics},
+	{name: "stale_issues", update: updateStaleIssueMetrics},
+	{name: "issue_assignees", update: updateIssueAssigneeMetrics},
+	{name: "first_response", update: updateFirstResponseMetrics},
+	{name: "issue_rates", update: updateIssueRateMetrics},
+	{name: "pull_reviews", update: updatePullReviewMetrics},
+	{name: "draft_pulls", update: updateDraftPullMetrics},
+	{name: "time_to_merge", update: updateTimeToMergeMetrics},
+	{name: "pull_size", update: updatePullSizeMetrics},
+	{name: "bot_pulls", update: updateBotPullMetrics},
+	{name: "merge_queue", update: updateMergeQueueMetrics},
+	{name: "milestones", update: updateMilestoneMetrics},
+	{name: "discussions", update: updateDiscussionMetrics},
+	{name: "top_issues", update: updateTopIssueMetrics},
+	{name: "dependabot_alerts", update: updateDependabotAlertMetrics},
+	{name: "secret_scanning_alerts", update: updateSecretScanningAlertMetrics},
+	{name: "dependencies", update: updateDependencyMetrics},
+	{name: "dependents", update: updateDependentsMetrics},
+	{name: "security_features", update: updateSecurityFeatureMetrics},
+	{name: "community", update: updateCommunityMetrics},
+	{name: "outside_collaborators", update: updateOutsideCollaboratorMetrics},
+	{name: "events", update: updateEventMetrics},
+	{name: "dora", update: updateDORAMetrics},
+	{name: "environments", update: updateEnvironmentMetrics},
+	{name: "check_runs", update: updateCheckRunMetrics},
+	{name: "collaborators", update: updateCollaboratorMetrics},
+}
+
+type accountCollector struct {
+	name   string
+	update func(ctx context.Context, client *githubClient, opts *collectorOptions) error
+}
+
+// accountCollectors are optional collectors for the authenticated user and
+// the organizations given with --org, enabled with --collector.
+var accountCollectors = []accountCollector{
+	{name: "packages", update: updatePackageMetrics},
+	{name: "container_versions", update: updateContainerVersionMetrics},
+	{name: "org_webhooks", update: updateOrgWebhookMetrics},
+	{name: "review_requests", update: updateReviewRequestMetrics},
+	{name: "authored_pulls", update: updateAuthoredPullMetrics},
+	{name: "assigned_issues", update: updateAssignedIssueMetrics},
+	{name: "org_security_alerts", update: updateOrgSecurityAlertMetrics},
+	{name: "teams", update: updateTeamMetrics},
+	{name: "org_invitations", update: updateOrgInvitationMetrics},
+	{name: "org_2fa", update: updateOrg2FAMetrics},
+	{name: "org_outside_collaborators", update: updateOrgOutsideCollaboratorMetrics},
+	{name: "org_apps", update: updateOrgAppMetrics},
+	{name: "org_plan", update: updateOrgPlanMetrics},
+	{name: "enterprise_licenses", update: updateEnterpriseLicenseMetrics},
+	{name: "copilot", update: updateCopilotMetrics},
+	{name: "billing", update: updateBillingMetrics},
+	{name: "gists", update: updateGistMetrics},
+	{name: "sponsors", update: updateSponsorMetrics},
+	{name: "followers", update: updateFollowerMetrics},
+	{name: "starred", update: updateStarredMetrics},
+	{name: "watching", update: updateWatchingMetrics},
+	{name: "status", update: updateStatusPageMetrics},
+}
+
+type generateCommand struct {
+	Output             string   `arg:"-o,--output,env:GITHUB_EXPORTER_OUTPUT" placeholder:"FILE"`
+	PushgatewayURL     url.URL  `arg:"-p,--pushgateway-url,env:GITHUB_EXPORTER_PUSHGATEWAY_URL" placeholder:"URL"`
+	PushgatewayRetries int      `arg:"-r,--pushgateway-retries,env:GITHUB_EXPORTER_PUSHGATEWAY_RETRIES" default:"1" placeholder:"RETRIES"`
+	Diff               string   `arg:"--diff,env:GITHUB_EXPORTER_DIFF" placeholder:"FILE" help:"Print series that changed compared to a previous metrics file"`
+	Format             string   `arg:"--format,env:GITHUB_EXPORTER_FORMAT" default:"text" placeholder:"FORMAT" help:"Output format, text or proto for delimited protobuf"`
+	FailOn             []string `arg:"--fail-on,env:GITHUB_EXPORTER_FAIL_ON" placeholder:"CONCLUSION" help:"Exit non-zero if any workflow's latest run has one of these conclusions, e.g. failure,timed_out"`
+}
+
+type serveCommand struct {
+	Addr             string        `arg:"-l,--listen,env:GITHUB_EXPORTER_LISTEN" default:":9448" placeholder:"ADDRESS:PORT"`
+	Interval         time.Duration `arg:"-i,--interval,env:GITHUB_EXPORTER_INTERVAL" default:"15m" placeholder:"INTERVAL"`
+	Demo             bool          `arg:"--demo,env:GITHUB_EXPORTER_DEMO" help:"Serve synthetic data for every metric without a token"`
+	MaxScrapes       int           `arg:"--max-scrapes,env:GITHUB_EXPORTER_MAX_SCRAPES" default:"4" placeholder:"N" help:"Maximum concurrent /metrics requests, further requests get a 503"`
+	ScrapeTimeout    time.Duration `arg:"--scrape-timeout,env:GITHUB_EXPORTER_SCRAPE_TIMEOUT" default:"30s" placeholder:"DURATION" help:"Time limit for serving a /metrics request"`
+	StateFile        string        `arg:"--state-file,env:GITHUB_EXPORTER_STATE_FILE" placeholder:"FILE" help:"Save metrics after each cycle and restore them on startup"`
+	Debug            bool          `arg:"--debug,env:GITHUB_EXPORTER_DEBUG" help:"Serve internal counters on /debug/vars"`
+	AdaptiveInterval bool          `arg:"--adaptive-interval,env:GITHUB_EXPORTER_ADAPTIVE_INTERVAL" help:"Stretch the interval when a cycle would use more than the token's hourly rate limit"`
+}
+
+// adaptInterval returns base, or the longer interval needed for the rate limit
+// one cycle used, measured between before and after, to be sustainable within
+// the token's hourly limit.
+func adaptInterval(base time.Duration, before, after *github.RateLimits) time.Duration {
+	interval := base
+	for _, rates := range [][2]*github.Rate{
+		{before.GetCore(), after.GetCore()},
+		{before.GetGraphQL(), after.GetGraphQL()},
+	} {
+		// A reset during the cycle makes the difference meaningless.
+		if rates[0] == nil || rates[1] == nil || !rates[0].Reset.Equal(rates[1].Reset) || rates[1].Limit <= 0 {
+			continue
+		}
+		used := rates[0].Remaining - rates[1].Remaining
+		if used > 0 {
+			interval = max(interval, time.Hour*time.Duration(used)/time.Duration(rates[1].Limit))
+		}
+	}
+	return interval
+}
+
+type checkCommand struct{}
+
+type versionCommand struct{}
+
+type describeMetricsCommand struct{}
+
+type reportCommand struct {
+	Format     string        `arg:"--format,env:GITHUB_EXPORTER_REPORT_FORMAT" default:"text" placeholder:"FORMAT" help:"Output format, text or markdown"`
+	ReleaseAge time.Duration `arg:"--release-age,env:GITHUB_EXPORTER_REPORT_RELEASE_AGE" default:"4320h" placeholder:"DURATION" help:"Report repositories whose latest release is older than this"`
+}
+
+type healthcheckCommand struct {
+	URL string `arg:"--url,env:GITHUB_EXPORTER_HEALTHCHECK_URL" default:"http://localhost:9448" placeholder:"URL" help:"Base URL of the running exporter"`
+}
+
+type completionCommand struct {
+	Shell string `arg:"positional,required" placeholder:"SHELL" help:"bash, zsh or fish"`
+}
+
+type mainCommand struct {
+	Token           string                  `arg:"-t,--token,env:GITHUB_TOKEN" placeholder:"TOKEN"`
+	APIURL          url.URL                 `arg:"--api-url,env:GITHUB_API_URL" placeholder:"URL" help:"GitHub API base URL, e.g. https://github.example.com/api/v3 for GitHub Enterprise Server"`
+	RequestTimeout  time.Duration           `arg:"--request-timeout,env:GITHUB_EXPORTER_REQUEST_TIMEOUT" default:"30s" placeholder:"DURATION" help:"Timeout for each GitHub API request, or 0 for none"`
+	Verbose         bool                    `arg:"-v,--verbose,env:GITHUB_EXPORTER_VERBOSE" help:"Enable verbose logging"`
+	DryRun          bool                    `arg:"--dry-run" help:"Print which collectors would run and roughly how many API calls they make, then exit"`
+	Record          string                  `arg:"--record,env:GITHUB_EXPORTER_RECORD" placeholder:"DIR" help:"Record GitHub API responses to a directory"`
+	Replay          string                  `arg:"--replay,env:GITHUB_EXPORTER_REPLAY" placeholder:"DIR" help:"Replay recorded GitHub API responses instead of calling the API"`
+	Config          string                  `arg:"--config,env:GITHUB_EXPORTER_CONFIG" placeholder:"FILE" help:"JSON configuration file with per-repository overrides"`
+	Version         bool                    `arg:"-V,--version" help:"Print version information"`
+	Generate        *generateCommand        `arg:"subcommand:generate"`
+	Serve           *serveCommand           `arg:"subcommand:serve"`
+	Check           *checkCommand           `arg:"subcommand:check" help:"Validate the token and enabled collectors"`
+	VersionCommand  *versionCommand         `arg:"subcommand:version" help:"Print version and build information"`
+	DescribeMetrics *describeMetricsCommand `arg:"subcommand:describe-metrics" help:"List every metric with its help text and labels"`
+	Completion      *completionCommand      `arg:"subcommand:completion" help:"Print a shell completion script"`
+	Healthcheck     *healthcheckCommand     `arg:"subcommand:healthcheck" help:"Exit 0 if a running exporter is ready, for container health checks"`
+	Report          *reportCommand          `arg:"subcommand:report" help:"Print a summary of problems found by the collectors"`
+	collectorOptions
+}
+
+func main() {
+	var args mainCommand
+	p := arg.MustParse(&args)
+
+	// Inside GitHub Actions, default to the workflow's repository. Outside it,
+	// GITHUB_REPOSITORY is too common a variable name to switch modes on.
+	if args.Repository == "" && os.Getenv("GITHUB_ACTIONS") == "true" {
+		args.Repository = os.Getenv("GITHUB_REPOSITORY")
+	}
+
+	if args.Version || args.VersionCommand != nil {
+		writeVersion(os.Stdout)
+		os.Exit(0)
+	}
+
+	if args.DescribeMetrics != nil {
+		describeMetrics(os.Stdout)
+		os.Exit(0)
+	}
+
+	if args.Healthcheck != nil {
+		if err := healthcheck(args.Healthcheck.URL); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	if args.Completion != nil {
+		if err := writeCompletion(os.Stdout, args.Completion.Shell); err != nil {
+			p.WriteUsage(os.Stderr)
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	if args.Token == "" {
+		args.Token = fetchGitHubToken()
+	}
+
+	demo := args.Serve != nil && args.Serve.Demo
+	if args.Token == "" && args.Replay == "" && !demo {
+		p.WriteUsage(os.Stderr)
+		fmt.Fprintln(os.Stderr, "error: --token is required (or environment variable GITHUB_TOKEN)")
+		os.Exit(1)
+	}
+
+	for _, name := range args.Collectors {
+		if !knownCollector(name) {
+			p.WriteUsage(os.Stderr)
+			fmt.Fprintf(os.Stderr, "error: unknown collector %q\n", name)
+			os.Exit(1)
+		}
+	}
+
+	if args.Generate != nil && args.Generate.Format != "text" && args.Generate.Format != "proto" {
+		p.WriteUsage(os.Stderr)
+		fmt.Fprintf(os.Stderr, "error: unsupported output format %q\n", args.Generate.Format)
+		os.Exit(1)
+	}
+
+	if args.Generate != nil && args.Generate.Diff != "" {
+		switch {
+		case args.Generate.Output == "-":
+			p.WriteUsage(os.Stderr)
+			fmt.Fprintln(os.Stderr, "error: --diff prints to stdout and cannot be combined with --output -")
+			os.Exit(1)
+		case args.Generate.Format != "text":
+			p.WriteUsage(os.Stderr)
+			fmt.Fprintln(os.Stderr, "error: --diff compares text exposition files and cannot be combined with --format proto")
+			os.Exit(1)
+		}
+	}
+
+	if args.Report != nil && args.Report.Format != "text" && args.Report.Format != "markdown" {
+		p.WriteUsage(os.Stderr)
+		fmt.Fprintf(os.Stderr, "error: unsupported report format %q\n", args.Report.Format)
+		os.Exit(1)
+	}
+
+	if args.WorkflowConclusionMode != "all" && args.WorkflowConclusionMode != "match" {
+		p.WriteUsage(os.Stderr)
+		fmt.Fprintf(os.Stderr, "error: unsupported workflow conclusion mode %q\n", args.WorkflowConclusionMode)
+		os.Exit(1)
+	}
+
+	// --fail-on reads the exported conclusion series, so a conclusion left
+	// out by --workflow-conclusion could never fail the check.
+	if args.Generate != nil && len(args.WorkflowConclusions) > 0 {
+		for _, failOn := range args.Generate.FailOn {
+			for _, conclusion := range strings.Split(failOn, ",") {
+				if !slices.Contains(args.WorkflowConclusions, conclusion) {
+					p.WriteUsage(os.Stderr)
+					fmt.Fprintf(os.Stderr, "error: --fail-on %s requires --workflow-conclusion %s\n", conclusion, conclusion)
+					os.Exit(1)
+				}
+			}
+		}
+	}
+
+	if args.PerPage < 1 || args.PerPage > 100 {
+		p.WriteUsage(os.Stderr)
+		fmt.Fprintf(os.Stderr, "error: --per-page must be between 1 and 100, got %d\n", args.PerPage)
+		os.Exit(1)
+	}
+
+	if args.ReleaseAssetReleases < 1 || args.ReleaseAssetReleases > 100 {
+		p.WriteUsage(os.Stderr)
+		fmt.Fprintf(os.Stderr, "error: --release-asset-releases must be between 1 and 100, got %d\n", args.ReleaseAssetReleases)
+		os.Exit(1)
+	}
+
+	if args.Repository != "" && !strings.Contains(args.Repository, "/") {
+		p.WriteUsage(os.Stderr)
+		fmt.Fprintf(os.Stderr, "error: --repository must be OWNER/REPO, got %q\n", args.Repository)
+		os.Exit(1)
+	}
+
+	// exposed is what generate and serve output, after any relabeling and
+	// the series limit.
+	var exposed prometheus.Gatherer = registry
+	if args.Config != "" {
+		cfg, err := loadConfig(args.Config)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		args.Overrides = cfg.Repositories
+		if len(cfg.Relabel) > 0 {
+			exposed = relabelingGatherer{Gatherer: registry, rules: cfg.Relabel}
+		}
+	}
+	if args.SeriesLimit > 0 {
+		exposed = &seriesLimitingGatherer{Gatherer: exposed, limit: args.SeriesLimit, dropped: make(map[string]bool)}
+	}
+
+	if args.Repository == "" {
+		warnIfIncompatibleToken(args.Token)
+	}
+
+	ctx := context.Background()
+
+	var transport http.RoundTripper = http.DefaultTransport
+	switch {
+	case args.Replay != "":
+		transport = &replayRoundTripper{dir: args.Replay}
+	case args.Record != "":
+		transport = &recordingRoundTripper{dir: args.Record, wrapped: transport}
+	}
+	if args.Verbose {
+		transport = &loggingRoundTripper{wrapped: transport}
+	}
+	client := newGitHubClient(args.Token, args.APIURL, args.RequestTimeout, transport)
+
+	if args.DryRun {
+		if err := dryRun(ctx, client, &args.collectorOptions); err != nil {
+			log.Fatalf("Error listing repositories: %v", err)
+		}
+		os.Exit(0)
+	}
+
+	if args.Check != nil {
+		if !runCheck(ctx, client, &args.collectorOptions) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if !demo && args.Repository == "" {
+		if err := checkToken(ctx, client, &args.collectorOptions); err != nil {
+			log.Printf("Warning: could not check token: %v", err)
+		}
+	}
+
+	switch {
+	case args.Generate != nil:
+		// Read the previous file first, as --output may overwrite it.
+		var previous map[string]*dto.MetricFamily
+		if args.Generate.Diff != "" {
+			var err error
+			if previous, err = readMetricsFile(args.Generate.Diff); err != nil {
+				log.Fatalf("Error reading previous metrics: %v", err)
+			}
+		}
+
+		if err := updateGitHubMetrics(client, ctx, &args.collectorOptions, &repoRotation{}); err != nil {
+			log.Fatalf("Error fetching metrics: %v", err)
+		}
+
+		// If no output, pushgateway or diff is specified, write to stdout
+		if args.Generate.Output == "" && args.Generate.PushgatewayURL.String() == "" && args.Generate.Diff == "" {
+			args.Generate.Output = "-"
+		}
+
+		format := expfmt.NewFormat(expfmt.TypeTextPlain)
+		if args.Generate.Format == "proto" {
+			format = expfmt.NewFormat(expfmt.TypeProtoDelim)
+		}
+
+		if args.Generate.Output == "-" {
+			if err := writeMetrics(os.Stdout, exposed, format); err != nil {
+				log.Fatalf("Error writing metrics: %v", err)
+			}
+		} else if args.Generate.Output != "" {
+			if err := writeMetricsFile(args.Generate.Output, exposed, format); err != nil {
+				log.Fatalf("Error writing metrics: %v", err)
+			}
+		}
+
+		if args.Generate.PushgatewayURL.String() != "" {
+			pushHTTPClient := http.DefaultClient
+			pusher := push.New(args.Generate.PushgatewayURL.String(), "github").Client(pushHTTPClient).Gatherer(exposed)
+			var err error
+			for i := 1; i < args.Generate.PushgatewayRetries; i++ {
+				if err = pusher.Push(); err == nil {
+					break
+				}
+				log.Printf("Error pushing metrics, retrying (%d/%d): %v", i, args.Generate.PushgatewayRetries, err)
+				time.Sleep(2 * time.Second)
+			}
+			if err != nil {
+				log.Fatalf("Error pushing metrics after %d retries: %v", args.Generate.PushgatewayRetries, err)
+			}
+		}
+
+		if args.Generate.Diff != "" {
+			if err := writeMetricsDiff(os.Stdout, previous, exposed); err != nil {
+				log.Fatalf("Error comparing metrics: %v", err)
+			}
+		}
+
+		if len(args.Generate.FailOn) > 0 {
+			failing, err := workflowsWithConclusion(registry, args.Generate.FailOn)
+			if err != nil {
+				log.Fatalf("Error checking workflow conclusions: %v", err)
+			}
+			for _, workflow := range failing {
+				log.Printf("Workflow %s", workflow)
+			}
+			if len(failing) > 0 {
+				os.Exit(1)
+			}
+		}
+
+	case args.Report != nil:
+		if err := updateGitHubMetrics(client, ctx, &args.collectorOptions, &repoRotation{}); err != nil {
+			log.Fatalf("Error fetching metrics: %v", err)
+		}
+
+		sections, err := buildReport(registry, args.Report.ReleaseAge)
+		if err != nil {
+			log.Fatalf("Error building report: %v", err)
+		}
+		if err := writeReport(os.Stdout, sections, args.Report.Format); err != nil {
+			log.Fatalf("Error writing report: %v", err)
+		}
+
+	case args.Serve != nil:
+		var ready atomic.Bool
+		if demo {
+			populateDemoMetrics()
+			ready.Store(true)
+		} else {
+			if args.Serve.StateFile != "" {
+				if err := restoreMetrics(args.Serve.StateFile); err != nil && !errors.Is(err, fs.ErrNotExist) {
+					log.Printf("Error restoring metrics from %s: %v", args.Serve.StateFile, err)
+				}
+			}
+
+			go func() {
+				interval := args.Serve.Interval
+				effectiveInterval.Set(interval.Seconds())
+				var rotation repoRotation
+
+				for {
+					start := time.Now()
+					var before *github.RateLimits
+					if args.Serve.AdaptiveInterval {
+						before, _, _ = client.RateLimit.Get(ctx)
+					}
+
+					log.Printf("[%s] Updating GitHub metrics", start.Format(time.RFC3339))
+					if err := updateGitHubMetrics(client, ctx, &args.collectorOptions, &rotation); err != nil {
+						log.Printf("[%s] Error fetching GitHub metrics: %v", time.Now().Format(time.RFC3339), err)
+					}
+					ready.Store(true)
+
+					if args.Serve.StateFile != "" {
+						if err := prometheus.WriteToTextfile(args.Serve.StateFile, registry); err != nil {
+							log.Printf("Error saving metrics to %s: %v", args.Serve.StateFile, err)
+						}
+					}
+
+					if before != nil {
+						if after, _, err := client.RateLimit.Get(ctx); err == nil {
+							if adapted := adaptInterval(args.Serve.Interval, before, after); adapted != interval {
+								interval = adapted
+								log.Printf("Effective interval is now %s", interval)
+								effectiveInterval.Set(interval.Seconds())
+							}
+						}
+					}
+
+					time.Sleep(time.Until(start.Add(interval)))
+				}
+			}()
+		}
+
+		var ln net.Listener
+		var err error
+		if os.Getenv("LISTEN_FDS") == "1" {
+			ln, err = activationListener()
+		} else {
+			ln, err = net.Listen("tcp", args.Serve.Addr)
+		}
+		if err != nil {
+			log.Fatalf("Error listening on %s: %v", args.Serve.Addr, err)
+		}
+		defer func() {
+			if err := ln.Close(); err != nil {
+				log.Printf("Error closing listener: %v", err)
+			}
+		}()
+
+		// A dedicated mux keeps the /debug/vars handler expvar registers on
+		// the default mux hidden unless --debug is given.
+		mux := http.NewServeMux()
+		metricsHandler := promhttp.HandlerFor(exposed, promhttp.HandlerOpts{
+			Registry:            registry,
+			MaxRequestsInFlight: args.Serve.MaxScrapes,
+			Timeout:             args.Serve.ScrapeTimeout,
+		})
+		mux.Handle("/metrics", promhttp.InstrumentHandlerInFlight(scrapesInFlight,
+			promhttp.InstrumentHandlerDuration(scrapeDuration, metricsHandler)))
+		mux.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) {
+			if !ready.Load() {
+				http.Error(w, "initial collection in progress", http.StatusServiceUnavailable)
+				return
+			}
+			fmt.Fprintln(w, "ok")
+		})
+		if args.Serve.Debug {
+			mux.Handle("/debug/vars", expvar.Handler())
+		}
+		log.Fatal(http.Serve(ln, mux))
+
+	default:
+		p.WriteHelp(os.Stdout)
+		os.Exit(1)
+	}
+}
+
+// collectorScopes are the classic personal access token scopes required by
+// collectors, in addition to repo and notifications.
+var collectorScopes = map[string][]string{
+	"packages":                  {"read:packages"},
+	"container_versions":        {"read:packages"},
+	"webhooks":                  {"read:repo_hook"},
+	"org_webhooks":              {"admin:org_hook"},
+	"org_security_alerts":       {"security_events"},
+	"teams":                     {"read:org"},
+	"org_invitations":           {"admin:org"},
+	"org_2fa":                   {"read:org"},
+	"org_outside_collaborators": {"read:org"},
+	"org_apps":                  {"admin:org"},
+	"org_plan":                  {"read:org"},
+	"enterprise_licenses":       {"read:enterprise"},
+	"copilot":                   {"manage_billing:copilot"},
+	"billing":                   {"user"},
+	"sponsors":                  {"read:user"},
+}
+
+// impliedScopes maps a scope to the broader scopes that include it.
+var impliedScopes = map[string][]string{
+	"read:packages":          {"write:packages"},
+	"write:packages":         {"delete:packages"},
+	"read:repo_hook":         {"write:repo_hook", "admin:repo_hook"},
+	"write:repo_hook":        {"admin:repo_hook"},
+	"read:org":               {"write:org", "admin:org"},
+	"write:org":              {"admin:org"},
+	"read:enterprise":        {"admin:enterprise"},
+	"manage_billing:copilot": {"admin:org"},
+	"read:user":              {"user"},
+	"security_events":        {"repo"},
+}
+
+func hasScope(granted []string, scope string) bool {
+	if slices.Contains(granted, scope) {
+		return true
+	}
+	for _, broader := range impliedScopes[scope] {
+		if hasScope(granted, broader) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkToken exports the token expiration and the scopes of a classic personal
+// access token, and warns about scopes missing for the enabled collectors.
+func checkToken(ctx context.Context, client *githubClient, opts *collectorOptions) error {
+	_, resp, err := client.Users.Get(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	if !resp.TokenExpiration.IsZero() {
+		tokenExpiration.Set(float64(resp.TokenExpiration.Unix()))
+	}
+
+	granted, ok := tokenScopes(resp)
+	if !ok {
+		return nil
+	}
+	for _, scope := range granted {
+		tokenScope.With(prometheus.Labels{"scope": scope}).Set(1)
+	}
+	for _, missing := range missingScopes(granted, opts) {
+		log.Printf("Warning: token is missing the %s scope", missing)
+	}
+
+	return nil
+}
+
+// tokenScopes returns the scopes of a classic personal access token. Other
+// token types do not report scopes.
+func tokenScopes(resp *github.Response) ([]string, bool) {
+	header, ok := resp.Header["X-Oauth-Scopes"]
+	if !ok || len(header) == 0 {
+		return nil, false
+	}
+
+	var granted []string
+	for _, scope := range strings.Split(header[0], ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			granted = append(granted, scope)
+		}
+	}
+	return granted, true
+}
+
+// missingScopes describes the scopes needed by the default metrics and the
+// enabled collectors that have not been granted.
+func missingScopes(granted []string, opts *collectorOptions) []string {
+	var missing []string
+	for _, scope := range []string{"repo", "notifications"} {
+		if !hasScope(granted, scope) {
+			missing = append(missing, scope)
+		}
+	}
+	for _, name := range opts.Collectors {
+		for _, scope := range collectorScopes[name] {
+			if !hasScope(granted, scope) {
+				missing = append(missing, fmt.Sprintf("%s (required by the %s collector)", scope, name))
+			}
+		}
+	}
+	return missing
+}
+
+func warnIfIncompatibleToken(token string) {
+	switch {
+	case strings.HasPrefix(token, "github_pat_"):
+		log.Println("Warning: fine-grained personal access tokens do not support the notifications API; notification metrics will fail")
+	case strings.HasPrefix(token, "ghs_"):
+		log.Println("Warning: GitHub App installation tokens do not support user-scoped APIs; notifications, issue, and repository metrics will fail")
+	case strings.HasPrefix(token, "ghu_"):
+		log.Println("Warning: GitHub App user-to-server tokens do not support the notifications API; notification metrics will fail")
+	}
+}
+
+type loggingRoundTripper struct {
+	wrapped http.RoundTripper
+}
+
+func (l loggingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	fmt.Fprintf(os.Stderr, "[%s] %s %s\n", time.Now().Format(time.RFC3339), req.Method, req.URL)
+	return l.wrapped.RoundTrip(req)
+}
+
+// newGitHubClient builds the client shared by every collector. REST and
+// GraphQL requests both go to apiURL, so pointing it at an httptest server
+// exercises collectors without live credentials.
+func newGitHubClient(token string, apiURL url.URL, timeout time.Duration, transport http.RoundTripper) *githubClient {
+	ts := oauth2.StaticTokenSource(
+		&oauth2.Token{AccessToken: token},
+	)
+	httpClient := &http.Client{
+		Transport: &userAgentRoundTripper{wrapped: &oauth2.Transport{Source: ts, Base: transport}},
+		Timeout:   timeout,
+	}
+
+	client := github.NewClient(httpClient)
+	if apiURL.String() != "" {
+		if !strings.HasSuffix(apiURL.Path, "/") {
+			apiURL.Path += "/"
+		}
+		client.BaseURL = &apiURL
+	}
+
+	wrapped := wrapGitHubClient(client)
+	wrapped.Web = &http.Client{
+		Transport: &userAgentRoundTripper{wrapped: transport},
+		Timeout:   timeout,
+	}
+	return wrapped
+}
+
+// githubClient is the API surface the collectors depend on. Each service
+// field is a narrow interface over the go-github service of the same name,
+// listing only the methods the exporter calls, so a test can stub a single
+// service without standing up the rest of the API.
+type githubClient struct {
+	restClient
+	BaseURL *url.URL
+	// Web fetches github.com pages that are not part of the API. It shares
+	// the timeout, User-Agent and record or replay transport, but never
+	// sends the token.
+	Web *http.Client
+
+	Actions        actionsService
+	Activity       activityService
+	Billing        billingService
+	Checks         checksService
+	CodeScanning   codeScanningService
+	Copilot        copilotService
+	Dependabot     dependabotService
+	Gists          gistsService
+	Issues         issuesService
+	Organizations  organizationsService
+	RateLimit      rateLimitService
+	Repositories   repositoriesService
+	Search         searchService
+	SecretScanning secretScanningService
+	Users          usersService
+}
+
+// restClient covers the raw requests made for endpoints go-github does not
+// wrap, and GraphQL queries that share its HTTP client.
+type restClient interface {
+	NewRequest(method, urlStr string, body any, opts ...github.RequestOption) (*http.Request, error)
+	Do(ctx context.Context, req *http.Request, v any) (*github.Response, error)
+	Client() *http.Client
+}
+
+type actionsService interface {
+	ListRepositoryWorkflowRuns(ctx context.Context, owner, repo string, opts *github.ListWorkflowRunsOptions) (*github.WorkflowRuns, *github.Response, error)
+	ListWorkflows(ctx context.Context, owner, repo string, opts *github.ListOptions) (*github.Workflows, *github.Response, error)
+}
+
+type activityService interface {
+	ListNotifications(ctx context.Context, opts *github.NotificationListOptions) ([]*github.Notification, *github.Response, error)
+	ListRepositoryEvents(ctx context.Context, owner, repo string, opts *github.ListOptions) ([]*github.Event, *github.Response, error)
+}
+
+type billingService interface {
+	GetActionsBillingOrg(ctx context.Context, org string) (*github.ActionBilling, *github.Response, error)
+	GetActionsBillingUser(ctx context.Context, user string) (*github.ActionBilling, *github.Response, error)
+	GetPackagesBillingOrg(ctx context.Context, org string) (*github.PackageBilling, *github.Response, error)
+	GetPackagesBillingUser(ctx context.Context, user string) (*github.PackageBilling, *github.Response, error)
+	GetStorageBillingOrg(ctx context.Context, org string) (*github.StorageBilling, *github.Response, error)
+	GetStorageBillingUser(ctx context.Context, user string) (*github.StorageBilling, *github.Response, error)
+}
+
+type checksService interface {
+	ListCheckRunsForRef(ctx context.Context, owner, repo, ref string, opts *github.ListCheckRunsOptions) (*github.ListCheckRunsResults, *github.Response, error)
+}
+
+type codeScanningService interface {
+	ListAlertsForOrg(ctx context.Context, org string, opts *github.AlertListOptions) ([]*github.Alert, *github.Response, error)
+}
+
+type copilotService interface {
+	GetCopilotBilling(ctx context.Context, org string) (*github.CopilotOrganizationDetails, *github.Response, error)
+	ListCopilotSeats(ctx context.Context, org string, opts *github.ListOptions) (*github.ListCopilotSeatsResponse, *github.Response, error)
+}
+
+type dependabotService interface {
+	ListOrgAlerts(ctx context.Context, org string, opts *github.ListAlertsOptions) ([]*github.DependabotAlert, *github.Response, error)
+	ListRepoAlerts(ctx context.Context, owner, repo string, opts *github.ListAlertsOptions) ([]*github.DependabotAlert, *github.Response, error)
+}
+
+type gistsService interface {
+	List(ctx context.Context, user string, opts *github.GistListOptions) ([]*github.Gist, *github.Response, error)
+}
+
+type issuesService interface {
+	ListMilestones(ctx context.Context, owner string, repo string, opts *github.MilestoneListOptions) ([]*github.Milestone, *github.Response, error)
+}
+
+type organizationsService interface {
+	Get(ctx context.Context, org string) (*github.Organization, *github.Response, error)
+	ListHookDeliveries(ctx context.Context, org string, id int64, opts *github.ListCursorOptions) ([]*github.HookDelivery, *github.Response, error)
+	ListHooks(ctx context.Context, org string, opts *github.ListOptions) ([]*github.Hook, *github.Response, error)
+	ListInstallations(ctx context.Context, org string, opts *github.ListOptions) (*github.OrganizationInstallations, *github.Response, error)
+	ListMembers(ctx context.Context, org string, opts *github.ListMembersOptions) ([]*github.User, *github.Response, error)
+	ListOutsideCollaborators(ctx context.Context, org string, opts *github.ListOutsideCollaboratorsOptions) ([]*github.User, *github.Response, error)
+	ListPackages(ctx context.Context, org string, opts *github.PackageListOptions) ([]*github.Package, *github.Response, error)
+	ListPendingOrgInvitations(ctx context.Context, org string, opts *github.ListOptions) ([]*github.Invitation, *github.Response, error)
+	PackageGetAllVersions(ctx context.Context, org, packageType, packageName string, opts *github.PackageListOptions) ([]*github.PackageVersion, *github.Response, error)
+}
+
+type rateLimitService interface {
+	Get(ctx context.Context) (*github.RateLimits, *github.Response, error)
+}
+
+type repositoriesService interface {
+	CompareCommits(ctx context.Context, owner, repo string, base, head string, opts *github.ListOptions) (*github.CommitsComparison, *github.Response, error)
+	Get(ctx context.Context, owner, repo string) (*github.Repository, *github.Response, error)
+	GetAllRulesets(ctx context.Context, owner, repo string, includesParents bool) ([]*github.Ruleset, *github.Response, error)
+	GetBranchProtection(ctx context.Context, owner, repo, branch string) (*github.Protection, *github.Response, error)
+	GetCombinedStatus(ctx context.Context, owner, repo, ref string, opts *github.ListOptions) (*github.CombinedStatus, *github.Response, error)
+	GetCommunityHealthMetrics(ctx context.Context, owner, repo string) (*github.CommunityHealthMetrics, *github.Response, error)
+	GetLatestPagesBuild(ctx context.Context, owner, repo string) (*github.PagesBuild, *github.Response, error)
+	GetLatestRelease(ctx context.Context, owner, repo string) (*github.RepositoryRelease, *github.Response, error)
+	GetVulnerabilityAlerts(ctx context.Context, owner, repository string) (bool, *github.Response, error)
+	ListByAuthenticatedUser(ctx context.Context, opts *github.RepositoryListByAuthenticatedUserOptions) ([]*github.Repository, *github.Response, error)
+	ListCollaborators(ctx context.Context, owner, repo string, opts *github.ListCollaboratorsOptions) ([]*github.User, *github.Response, error)
+	ListEnvironments(ctx context.Context, owner, repo string, opts *github.EnvironmentListOptions) (*github.EnvResponse, *github.Response, error)
+	ListHookDeliveries(ctx context.Context, owner, repo string, id int64, opts *github.ListCursorOptions) ([]*github.HookDelivery, *github.Response, error)
+	ListHooks(ctx context.Context, owner, repo string, opts *github.ListOptions) ([]*github.Hook, *github.Response, error)
+	ListKeys(ctx context.Context, owner string, repo string, opts *github.ListOptions) ([]*github.Key, *github.Response, error)
+	ListReleases(ctx context.Context, owner, repo string, opts *github.ListOptions) ([]*github.RepositoryRelease, *github.Response, error)
+}
+
+type searchService interface {
+	Issues(ctx context.Context, query string, opts *github.SearchOptions) (*github.IssuesSearchResult, *github.Response, error)
+}
+
+type secretScanningService interface {
+	ListAlertsForOrg(ctx context.Context, org string, opts *github.SecretScanningAlertListOptions) ([]*github.SecretScanningAlert, *github.Response, error)
+	ListAlertsForRepo(ctx context.Context, owner, repo string, opts *github.SecretScanningAlertListOptions) ([]*github.SecretScanningAlert, *github.Response, error)
+}
+
+type usersService interface {
+	Get(ctx context.Context, user string) (*github.User, *github.Response, error)
+	ListPackages(ctx context.Context, user string, opts *github.PackageListOptions) ([]*github.Package, *github.Response, error)
+	PackageGetAllVersions(ctx context.Context, user, packageType, packageName string, opts *github.PackageListOptions) ([]*github.PackageVersion, *github.Response, error)
+}
+
+// wrapGitHubClient exposes a go-github client through the collector interfaces.
+func wrapGitHubClient(client *github.Client) *githubClient {
+	return &githubClient{
+		restClient:     client,
+		BaseURL:        client.BaseURL,
+		Actions:        client.Actions,
+		Activity:       client.Activity,
+		Billing:        client.Billing,
+		Checks:         client.Checks,
+		CodeScanning:   client.CodeScanning,
+		Copilot:        client.Copilot,
+		Dependabot:     client.Dependabot,
+		Gists:          client.Gists,
+		Issues:         client.Issues,
+		Organizations:  client.Organizations,
+		RateLimit:      client.RateLimit,
+		Repositories:   client.Repositories,
+		Search:         client.Search,
+		SecretScanning: client.SecretScanning,
+		Users:          client.Users,
+	}
+}
+
+var userAgent = "github_exporter/" + Version + " (+https://github.com/josh/github_exporter)"
+
+// userAgentRoundTripper identifies the exporter on both REST and GraphQL
+// requests, as GitHub asks API clients to do.
+type userAgentRoundTripper struct {
+	wrapped http.RoundTripper
+}
+
+func (u userAgentRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", userAgent)
+	return u.wrapped.RoundTrip(req)
+}
+
+func fetchGitHubToken() string {
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		return token
+	}
+	if token := os.Getenv("GH_TOKEN"); token != "" {
+		return token
+	}
+
+	if credsDir := os.Getenv("CREDENTIALS_DIRECTORY"); credsDir != "" {
+		filenames := []string{"GITHUB_TOKEN", "GH_TOKEN", "github-token", "gh-token"}
+		for _, filename := range filenames {
+			filepath := credsDir + "/" + filename
+			if data, err := os.ReadFile(filepath); err == nil {
+				token := string(bytes.TrimSpace(data))
+				if token != "" {
+					return token
+				}
+			}
+		}
+	}
+
+	return ""
+}
+
+// Debug variables served on /debug/vars with serve --debug.
+var (
+	cacheLookups       = expvar.NewMap("cache_lookups")
+	collectorsInFlight = expvar.NewInt("collectors_in_flight")
+	collectorRuns      = expvar.NewMap("collector_runs")
+	collectorSeconds   = expvar.NewMap("collector_seconds")
+)
+
+func init() {
+	expvar.Publish("cache_hit_rate", expvar.Func(func() any {
+		rates := make(map[string]float64)
+		for _, cache := range []string{"workflows", "repo_list"} {
+			hits, misses := expvarInt(cacheLookups, cache+"_hits"), expvarInt(cacheLookups, cache+"_misses")
+			if hits+misses > 0 {
+				rates[cache] = float64(hits) / float64(hits+misses)
+			}
+		}
+		return rates
+	}))
+}
+
+func expvarInt(m *expvar.Map, key string) int64 {
+	if v, ok := m.Get(key).(*expvar.Int); ok {
+		return v.Value()
+	}
+	return 0
+}
+
+// timeCollector runs update, recording how long the collector took.
+func timeCollector(name string, update func() error) error {
+	collectorsInFlight.Add(1)
+	defer collectorsInFlight.Add(-1)
+
+	start := time.Now()
+	err := update()
+	collectorRuns.Add(name, 1)
+	collectorSeconds.AddFloat(name, time.Since(start).Seconds())
+	return err
+}
+
+func updateGitHubMetrics(client *githubClient, ctx context.Context, opts *collectorOptions, rotation *repoRotation) error {
+	deferred := deferredCollectors(ctx, client, opts)
+	resetMergedPullCache()
+
+	g, ctx := errgroup.WithContext(ctx)
+
+	// The GITHUB_TOKEN of an Actions workflow cannot read notifications.
+	if opts.Repository == "" {
+		g.Go(func() error {
+			if err := timeCollector("notifications", func() error { return updateNotificationsMetrics(ctx, client, opts) }); err != nil {
+				return fmt.Errorf("notifications metrics: %w", err)
+			}
+			return nil
+		})
+	}
+
+	g.Go(func() error {
+		if err := timeCollector("repository", func() error { return updateRepositoryMetrics(ctx, client, opts) }); err != nil {
+			return fmt.Errorf("repository metrics: %w", err)
+		}
+		return nil
+	})
+
+	for _, c := range accountCollectors {
+		if !opts.enabled(c.name) || collectorIsSkipped(c.name, "") || deferred[c.name] {
+			continue
+		}
+		g.Go(func() error {
+			if err := timeCollector(c.name, func() error { return c.update(ctx, client, opts) }); permissionError(err) {
+				skipCollector(c.name, "", err)
+			} else if err != nil {
+				return fmt.Errorf("%s metrics: %w", c.name, err)
+			}
+			return nil
+		})
+	}
+
+	g.Go(func() error {
+		repoGroup, ctx := errgroup.WithContext(ctx)
+
+		collect := func(repo *github.Repository) {
+			repo = opts.applyOverride(repo)
+
+			if !opts.collectorDisabled("workflows", repo) {
+				repoGroup.Go(func() error {
+					if err := timeCollector("workflows", func() error { return updateWorkflowRunMetrics(ctx, client, opts, repo) }); err != nil {
+						return fmt.Errorf("workflow metrics for %s: %w", repo.GetFullName(), err)
+					}
+					return nil
+				})
+			}
+
+			for _, c := range repoCollectors {
+				if !opts.enabled(c.name) || collectorIsSkipped(c.name, repo.GetFullName()) || deferred[c.name] || opts.collectorDisabled(c.name, repo) {
+					continue
+				}
+				repoGroup.Go(func() error {
+					if err := timeCollector(c.name, func() error { return c.update(ctx, client, opts, repo) }); permissionError(err) {
+						skipCollector(c.name, repo.GetFullName(), err)
+					} else if err != nil {
+						return fmt.Errorf("%s metrics for %s: %w", c.name, repo.GetFullName(), err)
+					}
+					return nil
+				})
+			}
+		}
+
+		// Without rotation, collection starts as each page of repositories
+		// arrives rather than after listing them all. Rotation needs the
+		// whole active list to pick from.
+		counts := make(map[repoCountKey]int)
+		var active []*github.Repository
+		for repo, err := range streamRepos(ctx, client, opts) {
+			if err != nil {
+				return errors.Join(fmt.Errorf("fetching repos: %w", err), repoGroup.Wait())
+			}
+			counts[newRepoCountKey(repo)]++
+			switch {
+			case repo.GetArchived():
+			case opts.ReposPerCycle == 0:
+				collect(repo)
+			default:
+				active = append(active, repo)
+			}
+		}
+
+		setRepoCountMetrics(counts)
+
+		if opts.ReposPerCycle > 0 {
+			for _, repo := range rotation.next(active, opts.ReposPerCycle) {
+				collect(repo)
+			}
+		}
+		return repoGroup.Wait()
+	})
+
+	return g.Wait()
+}
+
+// repoRotation remembers where --repos-per-cycle left off. The serve loop
+// keeps one across cycles; one-shot commands start from the beginning.
+type repoRotation struct {
+	start int
+}
+
+// next returns the next n repositories round-robin, or all of them if n is
+// zero.
+func (r *repoRotation) next(repos []*github.Repository, n int) []*github.Repository {
+	if n <= 0 || n >= len(repos) {
+		return repos
+	}
+
+	start := r.start % len(repos)
+	r.start = (start + n) % len(repos)
+
+	rotated := make([]*github.Repository, 0, n)
+	for i := range n {
+		rotated = append(rotated, repos[(start+i)%len(repos)])
+	}
+	return rotated
+}
+
+const repositoryGraphQLFragment = `
+fragment repositoryFields on Repository {
+	nameWithOwner
+	stargazerCount
+	forkCount
+	defaultBranchRef {
+		name
+		target {
+			... on Commit { committedDate }
+		}
+		branchProtectionRule @include(if: $withBranchProtection) {
+			requiresApprovingReviews
+			requiredApprovingReviewCount
+			requiresStatusChecks
+			requiredStatusChecks { context }
+			isAdminEnforced
+		}
+	}
+	latestRelease @include(if: $withReleases) {
+		tagName
+		publishedAt
+	}
+	openIssues: issues(states: OPEN) { totalCount }
+	closedIssues: issues(states: CLOSED) { totalCount }
+	openPulls: pullRequests(states: OPEN) { totalCount }
+	closedPulls: pullRequests(states: CLOSED) { totalCount }
+	oldestOpenIssue: issues(states: OPEN, first: 1, orderBy: {field: CREATED_AT, direction: ASC}) { nodes { createdAt } }
+	oldestOpenPull: pullRequests(states: OPEN, first: 1, orderBy: {field: CREATED_AT, direction: ASC}) { nodes { createdAt } }
+%s}`
+
+// issueLabelGraphQLFields looks up each --issue-label by name under an alias,
+// filling the %s in repositoryGraphQLFragment. Listing the repository's labels
+// instead would miss any beyond the first page.
+func issueLabelGraphQLFields(labels []string) string {
+	var b strings.Builder
+	for i, label := range labels {
+		// A JSON string is also a valid GraphQL string literal.
+		name, _ := json.Marshal(label)
+		fmt.Fprintf(&b, "\tlabel%d: label(name: %s) { issues(states: OPEN) { totalCount } }\n", i, name)
+	}
+	return b.String()
+}
+
+// repositoriesGraphQLQuery fetches everything the default metrics and
+// GraphQL-backed collectors need for up to 50 repositories at a time, so that
+// large accounts do not need a REST call per repository.
+const repositoriesGraphQLQuery = `
+query($login: String!, $cursor: String, $withReleases: Boolean!, $withBranchProtection: Boolean!, $perPage: Int!) {
+	user(login: $login) {
+		repositories(first: $perPage, after: $cursor, affiliations: OWNER, isArchived: false) {
+			nodes { ...repositoryFields }
+			pageInfo {
+				hasNextPage
+				endCursor
+			}
+		}
+	}
+}` + repositoryGraphQLFragment
+
+const singleRepositoryGraphQLQuery = `
+query($owner: String!, $name: String!, $withReleases: Boolean!, $withBranchProtection: Boolean!) {
+	repository(owner: $owner, name: $name) { ...repositoryFields }
+}` + repositoryGraphQLFragment
+
+type graphQLSingleRepositoryResponse struct {
+	Data struct {
+		Repository graphQLRepository `json:"repository"`
+	} `json:"data"`
+}
+
+type graphQLRepositoriesResponse struct {
+	Data struct {
+		User struct {
+			Repositories struct {
+				Nodes    []graphQLRepository `json:"nodes"`
+				PageInfo graphQLPageInfo     `json:"pageInfo"`
+			} `json:"repositories"`
+		} `json:"user"`
+	} `json:"data"`
+}
+
+type graphQLRepository struct {
+	NameWithOwner    string `json:"nameWithOwner"`
+	StargazerCount   int    `json:"stargazerCount"`
+	ForkCount        int    `json:"forkCount"`
+	DefaultBranchRef *struct {
+		Name   string `json:"name"`
+		Target struct {
+			CommittedDate time.Time `json:"committedDate"`
+		} `json:"target"`
+		BranchProtectionRule *struct {
+			RequiresApprovingReviews     bool `json:"requiresApprovingReviews"`
+			RequiredApprovingReviewCount int  `json:"requiredApprovingReviewCount"`
+			RequiresStatusChecks         bool `json:"requiresStatusChecks"`
+			RequiredStatusChecks         []struct {
+				Context string `json:"context"`
+			} `json:"requiredStatusChecks"`
+			IsAdminEnforced bool `json:"isAdminEnforced"`
+		} `json:"branchProtectionRule"`
+	} `json:"defaultBranchRef"`
+	LatestRelease *struct {
+		TagName     string    `json:"tagName"`
+		PublishedAt time.Time `json:"publishedAt"`
+	} `json:"latestRelease"`
+	OpenIssues      graphQLTotalCount     `json:"openIssues"`
+	ClosedIssues    graphQLTotalCount     `json:"closedIssues"`
+	OpenPulls       graphQLTotalCount     `json:"openPulls"`
+	ClosedPulls     graphQLTotalCount     `json:"closedPulls"`
+	OldestOpenIssue graphQLCreatedAtNodes `json:"oldestOpenIssue"`
+	OldestOpenPull  graphQLCreatedAtNodes `json:"oldestOpenPull"`
+	// Labels holds the --issue-label lookups in order, nil where the
+	// repository has no such label.
+	Labels []*struct {
+		Issues graphQLTotalCount `json:"issues"`
+	} `json:"-"`
+}
+
+// UnmarshalJSON collects the aliased label lookups into Labels.
+func (r *graphQLRepository) UnmarshalJSON(data []byte) error {
+	type fields graphQLRepository
+	if err := json.Unmarshal(data, (*fields)(r)); err != nil {
+		return err
+	}
+
+	var aliases map[string]json.RawMessage
+	if err := json.Unmarshal(data, &aliases); err != nil {
+		return err
+	}
+	r.Labels = nil
+	for i := 0; ; i++ {
+		raw, ok := aliases[fmt.Sprintf("label%d", i)]
+		if !ok {
+			return nil
+		}
+		var label *struct {
+			Issues graphQLTotalCount `json:"issues"`
+		}
+		if err := json.Unmarshal(raw, &label); err != nil {
+			return err
+		}
+		r.Labels = append(r.Labels, label)
+	}
+}
+
+type graphQLCreatedAtNodes struct {
+	Nodes []struct {
+		CreatedAt time.Time `json:"createdAt"`
+	} `json:"nodes"`
+}
+
+func writeMetrics(w io.Writer, reg prometheus.Gatherer, format expfmt.Format) error {
+	enc := expfmt.NewEncoder(w, format)
+	mfs, err := reg.Gather()
+	if err != nil {
+		return err
+	}
+	for _, mf := range mfs {
+		if err := enc.Encode(mf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeMetricsFile atomically replaces path with the gathered metrics, like
+// prometheus.WriteToTextfile but in any exposition format.
+func writeMetricsFile(path string, reg prometheus.Gatherer, format expfmt.Format) error {
+	if format.FormatType() == expfmt.TypeTextPlain {
+		return prometheus.WriteToTextfile(path, reg)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := writeMetrics(tmp, reg, format); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func activationListener() (net.Listener, error) {
+	if os.Getenv("LISTEN_PID") != fmt.Sprintf("%d", os.Getpid()) {
+		return nil, fmt.Errorf("expected LISTEN_PID=%d, but was %s", os.Getpid(), os.Getenv("LISTEN_PID"))
+	}
+
+	if os.Getenv("LISTEN_FDS") != "1" {
+		return nil, fmt.Errorf("expected LISTEN_FDS=1, but was %s", os.Getenv("LISTEN_FDS"))
+	}
+
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+	if len(names) != 1 {
+		return nil, fmt.Errorf("expected LISTEN_FDNAMES to set 1 name, but was '%s'", os.Getenv("LISTEN_FDNAMES"))
+	}
+
+	fd := 3
+	syscall.CloseOnExec(fd)
+	f := os.NewFile(uintptr(fd), names[0])
+
+	ln, err := net.FileListener(f)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := f.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close file: %w", err)
+	}
+
+	return ln, nil
+}
+
+func updateNotificationsMetrics(ctx context.Context, client *githubClient, opts *collectorOptions) error {
+	listOpts := &github.NotificationListOptions{ListOptions: github.ListOptions{PerPage: min(opts.PerPage, 50)}}
+
+	unreadCount := 0
+	reasonCounts := make(map[string]int)
+	repoCounts := make(map[string]int)
+	var oldestUnread time.Time
+	for {
+		notifications, resp, err := client.Activity.ListNotifications(ctx, listOpts)
+		if err != nil {
+			return err
+		}
+
+		for _, notification := range notifications {
+			if !notification.GetUnread() {
+				continue
+			}
+			unreadCount++
+			reasonCounts[notification.GetReason()]++
+			repoCounts[notification.GetRepository().GetFullName()]++
+			if updatedAt := notification.GetUpdatedAt().Time; oldestUnread.IsZero() || updatedAt.Before(oldestUnread) {
+				oldestUnread = updatedAt
+			}
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		listOpts.Page = resp.NextPage
+	}
+	notificationCount.With(prometheus.Labels{"unread": "true"}).Set(float64(unreadCount))
+
+	if oldestUnread.IsZero() {
+		notificationOldestUnreadAge.Set(0)
+	} else {
+		notificationOldestUnreadAge.Set(time.Since(oldestUnread).Seconds())
+	}
+
+	notificationReasonCount.Reset()
+	for reason, count := range reasonCounts {
+		notificationReasonCount.With(prometheus.Labels{"reason": reason}).Set(float64(count))
+	}
+
+	notificationRepoCount.Reset()
+	if opts.NotificationRepoLimit > 0 {
+		for repo, count := range capLabelCounts(repoCounts, opts.NotificationRepoLimit, "") {
+			notificationRepoCount.With(prometheus.Labels{"github_repo": repo}).Set(float64(count))
+		}
+	}
+
+	return nil
+}
+
+func updateRepositoryMetrics(ctx context.Context, client *githubClient, opts *collectorOptions) error {
+	if opts.Repository != "" {
+		owner, name, _ := strings.Cut(opts.Repository, "/")
+		variables := map[string]any{
+			"owner":                owner,
+			"name":                 name,
+			"withReleases":         opts.enabled("releases"),
+			"withBranchProtection": opts.enabled("branch_protection"),
+		}
+
+		var response graphQLSingleRepositoryResponse
+		if err := executeGraphQL(client, ctx, fmt.Sprintf(singleRepositoryGraphQLQuery, issueLabelGraphQLFields(opts.IssueLabels)), variables, &response); err != nil {
+			return err
+		}
+		setRepositoryMetrics(response.Data.Repository, opts)
+		return updateOverriddenBranchProtection(ctx, client, opts)
+	}
+
+	user, _, err := client.Users.Get(ctx, "")
+	if err != nil {
+		return err
+	}
+	username := user.GetLogin()
+
+	variables := map[string]any{
+		"login":                username,
+		"cursor":               nil,
+		"withReleases":         opts.enabled("releases"),
+		"withBranchProtection": opts.enabled("branch_protection"),
+		"perPage":              min(opts.PerPage, 50),
+	}
+
+	for {
+		var response graphQLRepositoriesResponse
+		if err := executeGraphQL(client, ctx, fmt.Sprintf(repositoriesGraphQLQuery, issueLabelGraphQLFields(opts.IssueLabels)), variables, &response); err != nil {
+			return err
+		}
+
+		repos := response.Data.User.Repositories
+		for _, repo := range repos.Nodes {
+			setRepositoryMetrics(repo, opts)
+		}
+
+		if !repos.PageInfo.HasNextPage {
+			break
+		}
+		variables["cursor"] = repos.PageInfo.EndCursor
+	}
+
+	return updateOverriddenBranchProtection(ctx, client, opts)
+}
+
+// updateOverriddenBranchProtection fetches branch protection over REST for
+// repositories configured to monitor a branch other than their default, which
+// the shared query cannot follow.
+func updateOverriddenBranchProtection(ctx context.Context, client *githubClient, opts *collectorOptions) error {
+	if !opts.enabled("branch_protection") {
+		return nil
+	}
+	for _, fullName := range slices.Sorted(maps.Keys(opts.Overrides)) {
+		override := opts.Overrides[fullName]
+		if override.Branch == "" || slices.Contains(override.DisableCollectors, "branch_protection") {
+			continue
+		}
+		if opts.Repository != "" && opts.Repository != fullName {
+			continue
+		}
+		owner, name, _ := strings.Cut(fullName, "/")
+		repo := &github.Repository{
+			FullName:      &fullName,
+			Name:          &name,
+			Owner:         &github.User{Login: &owner},
+			DefaultBranch: &override.Branch,
+		}
+		if err := updateBranchProtectionMetrics(ctx, client, opts, repo); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func setRepositoryMetrics(repo graphQLRepository, opts *collectorOptions) {
+	repoLabels := prometheus.Labels{"github_repo": repo.NameWithOwner}
+	repoStars.With(repoLabels).Set(float64(repo.StargazerCount))
+	repoForks.With(repoLabels).Set(float64(repo.ForkCount))
+	if repo.DefaultBranchRef != nil {
+		repoDefaultBranchCommitTimestamp.With(repoLabels).Set(float64(repo.DefaultBranchRef.Target.CommittedDate.Unix()))
+	}
+	if repo.LatestRelease != nil {
+		setLatestReleaseMetrics(repo.NameWithOwner, repo.LatestRelease.TagName, repo.LatestRelease.PublishedAt)
+	}
+	override := opts.Overrides[repo.NameWithOwner]
+	if opts.enabled("branch_protection") && repo.DefaultBranchRef != nil && override.Branch == "" && !slices.Contains(override.DisableCollectors, "branch_protection") {
+		labels := prometheus.Labels{
+			"github_repo": repo.NameWithOwner,
+			"branch":      repo.DefaultBranchRef.Name,
+		}
+		if rule := repo.DefaultBranchRef.BranchProtectionRule; rule != nil {
+			requiredReviews, requiredChecks := 0, 0
+			if rule.RequiresApprovingReviews {
+				requiredReviews = rule.RequiredApprovingReviewCount
+			}
+			if rule.RequiresStatusChecks {
+				requiredChecks = len(rule.RequiredStatusChecks)
+			}
+			setBranchProtectionMetrics(labels, true, requiredReviews, requiredChecks, rule.IsAdminEnforced)
+		} else {
+			setBranchProtectionMetrics(labels, false, 0, 0, false)
+		}
+	}
+
+	issueCount.With(prometheus.Labels{
+		"github_repo": repo.NameWithOwner,
+		"type":        "issue",
+		"state":       "open",
+	}).Set(float64(repo.OpenIssues.TotalCount))
+
+	issueCount.With(prometheus.Labels{
+		"github_repo": repo.NameWithOwner,
+		"type":        "issue",
+		"state":       "closed",
+	}).Set(float64(repo.ClosedIssues.TotalCount))
+
+	issueCount.With(prometheus.Labels{
+		"github_repo": repo.NameWithOwner,
+		"type":        "pull",
+		"state":       "open",
+	}).Set(float64(repo.OpenPulls.TotalCount))
+
+	issueCount.With(prometheus.Labels{
+		"github_repo": repo.NameWithOwner,
+		"type":        "pull",
+		"state":       "closed",
+	}).Set(float64(repo.ClosedPulls.TotalCount))
+
+	for issueType, oldest := range map[string]graphQLCreatedAtNodes{"issue": repo.OldestOpenIssue, "pull": repo.OldestOpenPull} {
+		labels := prometheus.Labels{
+			"github_repo": repo.NameWithOwner,
+			"type":        issueType,
+		}
+		if len(oldest.Nodes) == 0 {
+			issueOldestOpenAge.Delete(labels)
+			continue
+		}
+		issueOldestOpenAge.With(labels).Set(time.Since(oldest.Nodes[0].CreatedAt).Seconds())
+	}
+
+	for i, label := range opts.IssueLabels {
+		// A repository without the label has no issues with it either.
+		count := 0
+		if i < len(repo.Labels) && repo.Labels[i] != nil {
+			count = repo.Labels[i].Issues.TotalCount
+		}
+		issueLabelCount.With(prometheus.Labels{
+			"github_repo": repo.NameWithOwner,
+			"label":       label,
+		}).Set(float64(count))
+	}
+}
+
+// fetchRepos returns the repositories to collect metrics for.
+func fetchRepos(ctx context.Context, client *githubClient, opts *collectorOptions) ([]*github.Repository, error) {
+	var repos []*github.Repository
+	for repo, err := range streamRepos(ctx, client, opts) {
+		if err != nil {
+			return nil, err
+		}
+		repos = append(repos, repo)
+	}
+	return repos, nil
+}
+
+// repoListCache holds the last complete repository list, which changes far
+// less often than the metrics collected for each repository.
+var repoListCache struct {
+	sync.Mutex
+	repos   []*github.Repository
+	fetched time.Time
+}
+
+// streamRepos yields the repository given with --repository, or the
+// repositories owned by the user a page at a time. A list fetched within
+// --repo-list-ttl is reused instead, so with a TTL set the whole list is kept
+// in memory; repositories are trimmed to the fields collectors use to keep
+// that small.
+func streamRepos(ctx context.Context, client *githubClient, opts *collectorOptions) iter.Seq2[*github.Repository, error] {
+	return func(yield func(*github.Repository, error) bool) {
+		repoListCache.Lock()
+		cached, fetched := repoListCache.repos, repoListCache.fetched
+		repoListCache.Unlock()
+		if cached != nil && time.Since(fetched) < opts.RepoListTTL {
+			cacheLookups.Add("repo_list_hits", 1)
+			for _, repo := range cached {
+				if !yield(repo, nil) {
+					return
+				}
+			}
+			return
+		}
+
+		cacheLookups.Add("repo_list_misses", 1)
+
+		var listed []*github.Repository
+		for repo, err := range listRepos(ctx, client, opts) {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if opts.RepoListTTL > 0 {
+				listed = append(listed, repo)
+			}
+			if !yield(repo, nil) {
+				return
+			}
+		}
+
+		if opts.RepoListTTL == 0 {
+			return
+		}
+		repoListCache.Lock()
+		repoListCache.repos, repoListCache.fetched = listed, time.Now()
+		repoListCache.Unlock()
+	}
+}
+
+// listRepos yields repositories straight from the API for streamRepos.
+func listRepos(ctx context.Context, client *githubClient, opts *collectorOptions) iter.Seq2[*github.Repository, error] {
+	return func(yield func(*github.Repository, error) bool) {
+		if opts.Repository != "" {
+			owner, name, _ := strings.Cut(opts.Repository, "/")
+			repo, _, err := client.Repositories.Get(ctx, owner, name)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			yield(trimRepo(repo), nil)
+			return
+		}
+
+		listOpts := &github.RepositoryListByAuthenticatedUserOptions{
+			Type:      "owner",
+			Sort:      "full_name",
+			Direction: "asc",
+			ListOptions: github.ListOptions{
+				PerPage: opts.PerPage,
+			},
+		}
+		for {
+			repos, resp, err := client.Repositories.ListByAuthenticatedUser(ctx, listOpts)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			for _, repo := range repos {
+				if repo != nil && !yield(trimRepo(repo), nil) {
+					return
+				}
+			}
+
+			if resp.NextPage == 0 {
+				return
+			}
+			listOpts.Page = resp.NextPage
+		}
+	}
+}
+
+func trimRepo(repo *github.Repository) *github.Repository {
+	return &github.Repository{
+		Owner:          &github.User{Login: repo.GetOwner().Login},
+		Name:           repo.Name,
+		FullName:       repo.FullName,
+		HTMLURL:        repo.HTMLURL,
+		DefaultBranch:  repo.DefaultBranch,
+		Archived:       repo.Archived,
+		Private:        repo.Private,
+		Fork:           repo.Fork,
+		HasPages:       repo.HasPages,
+		HasDiscussions: repo.HasDiscussions,
+	}
+}
+
+type repoCountKey struct {
+	owner, visibility, archived string
+}
+
+func newRepoCountKey(repo *github.Repository) repoCountKey {
+	key := repoCountKey{owner: repo.GetOwner().GetLogin(), visibility: "public", archived: "false"}
+	if repo.GetPrivate() {
+		key.visibility = "private"
+	}
+	if repo.GetArchived() {
+		key.archived = "true"
+	}
+	return key
+}
+
+func setRepoCountMetrics(counts map[repoCountKey]int) {
+	for key, count := range counts {
+		repoCount.With(prometheus.Labels{
+			"owner":      key.owner,
+			"visibility": key.visibility,
+			"archived":   key.archived,
+		}).Set(float64(count))
+	}
+}
+
+type graphQLRequest struct {
+	Query     string         `json:"query"`
+	Variables map[string]any `json:"variables"`
+}
+
+func updateWorkflowRunMetrics(ctx context.Context, client *githubClient, opts *collectorOptions, repo *github.Repository) error {
+	owner, repoName := repo.GetOwner().GetLogin(), repo.GetName()
+
+	runs, _, err := client.Actions.ListRepositoryWorkflowRuns(ctx, owner, repoName, &github.ListWorkflowRunsOptions{
+		Branch: repo.GetDefaultBranch(),
+		Status: "completed",
+		ListOptions: github.ListOptions{
+			PerPage: 100,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	latestRuns := make(map[int64]*github.WorkflowRun)
+	for _, run := range runs.WorkflowRuns {
+		workflowID := run.GetWorkflowID()
+		if existing, ok := latestRuns[workflowID]; !ok || run.GetRunNumber() > existing.GetRunNumber() {
+			latestRuns[workflowID] = run
+		}
+	}
+
+	workflowNames, err := cachedWorkflowNames(ctx, client, opts, repo)
+	if err != nil {
+		return err
+	}
+
+	onlyWorkflows := opts.Overrides[repo.GetFullName()].Workflows
+	for workflowID, workflowName := range workflowNames {
+		if len(onlyWorkflows) > 0 && !slices.Contains(onlyWorkflows, workflowName) {
+			continue
+		}
+		if latestRun, ok := latestRuns[workflowID]; ok {
+			workflowRunNumber.With(prometheus.Labels{
+				"github_repo":   *repo.FullName,
+				"workflow_name": workflowName,
+			}).Set(float64(latestRun.GetRunNumber()))
+
+			workflowLastRunTimestamp.With(prometheus.Labels{
+				"github_repo":   *repo.FullName,
+				"workflow_name": workflowName,
+			}).Set(float64(latestRun.GetRunStartedAt().Unix()))
+
+			conclusions := opts.WorkflowConclusions
+			if len(conclusions) == 0 {
+				conclusions = defaultWorkflowConclusions
+			}
+			if opts.WorkflowConclusionMode == "match" {
+				workflowRunState.DeletePartialMatch(prometheus.Labels{
+					"github_repo":   *repo.FullName,
+					"workflow_name": workflowName,
+				})
+			}
+			for _, conclusion := range conclusions {
+				value := 0.0
+				if conclusion == latestRun.GetConclusion() {
+					value = 1.0
+				} else if opts.WorkflowConclusionMode == "match" {
+					continue
+				}
+				workflowRunState.With(prometheus.Labels{
+					"github_repo":                    *repo.FullName,
+					"workflow_name":                  workflowName,
+					"github_workflow_run_conclusion": conclusion,
+				}).Set(value)
+			}
+		}
+	}
+
+	return nil
+}
+
+var defaultWorkflowConclusions = []string{"action_required", "cancelled", "failure", "neutral",
+	"skipped", "stale", "startup_failure", "success", "timed_out"}
+
+// workflowCache holds each repository's workflow ID to name mapping, since
+// workflow definitions change far less often than their runs.
+var workflowCache = struct {
+	sync.Mutex
+	entries map[string]workflowCacheEntry
+}{entries: make(map[string]workflowCacheEntry)}
+
+type workflowCacheEntry struct {
+	names   map[int64]string
+	fetched time.Time
+}
+
+func cachedWorkflowNames(ctx context.Context, client *githubClient, opts *collectorOptions, repo *github.Repository) (map[int64]string, error) {
+	workflowCache.Lock()
+	entry, ok := workflowCache.entries[repo.GetFullName()]
+	workflowCache.Unlock()
+	if ok && time.Since(entry.fetched) < opts.WorkflowCacheTTL {
+		cacheLookups.Add("workflows_hits", 1)
+		return entry.names, nil
+	}
+	cacheLookups.Add("workflows_misses", 1)
+
+	names := make(map[int64]string)
+	listOpts := &github.ListOptions{PerPage: opts.PerPage}
+	for {
+		workflows, resp, err := client.Actions.ListWorkflows(ctx, repo.GetOwner().GetLogin(), repo.GetName(), listOpts)
+		if err != nil {
+			return nil, err
+		}
+		for _, workflow := range workflows.Workflows {
+			names[workflow.GetID()] = workflow.GetName()
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		listOpts.Page = resp.NextPage
+	}
+
+	workflowCache.Lock()
+	workflowCache.entries[repo.GetFullName()] = workflowCacheEntry{names: names, fetched: time.Now()}
+	workflowCache.Unlock()
+	return names, nil
+}
+
+const staleBranchesGraphQLQuery = `
+query($owner: String!, $name: String!, $cursor: String, $perPage: Int!) {
+	repository(owner: $owner, name: $name) {
+		refs(refPrefix: "refs/heads/", first: $perPage, after: $cursor) {
+			nodes {
+				name
+				target { ... on Commit { committedDate } }
+			}
+			pageInfo { hasNextPage endCursor }
+		}
+	}
+}`
+
+type graphQLBranchesResponse struct {
+	Data struct {
+		Repository struct {
+			Refs struct {
+				Nodes []struct {
+					Name   string `json:"name"`
+					Target struct {
+						CommittedDate time.Time `json:"committedDate"`
+					} `json:"target"`
+				} `json:"nodes"`
+				PageInfo graphQLPageInfo `json:"pageInfo"`
+			} `json:"refs"`
+		} `json:"repository"`
+	} `json:"data"`
+}
+
+type graphQLPageInfo struct {
+	HasNextPage bool   `json:"hasNextPage"`
+	EndCursor   string `json:"endCursor"`
+}
+
+func updateStaleBranchMetrics(ctx context.Context, client *githubClient, opts *collectorOptions, repo *github.Repository) error {
+	variables := map[string]any{
+		"owner":   repo.GetOwner().GetLogin(),
+		"name":    repo.GetName(),
+		"perPage": opts.PerPage,
+	}
+
+	cutoff := time.Now().Add(-opts.StaleBranchAge)
+	stale := 0
+	for {
+		var response graphQLBranchesResponse
+		if err := executeGraphQL(client, ctx, staleBranchesGraphQLQuery, variables, &response); err != nil {
+			return err
+		}
+
+		refs := response.Data.Repository.Refs
+		for _, ref := range refs.Nodes {
+			if ref.Name == repo.GetDefaultBranch() || ref.Target.CommittedDate.IsZero() {
+				continue
+			}
+			if ref.Target.CommittedDate.Before(cutoff) {
+				stale++
+			}
+		}
+
+		if !refs.PageInfo.HasNextPage {
+			break
+		}
+		variables["cursor"] = refs.PageInfo.EndCursor
+	}
+
+	staleBranchCount.With(prometheus.Labels{"github_repo": repo.GetFullName()}).Set(float64(stale))
+
+	return nil
+}
+
+// graphQLURL derives the GraphQL endpoint from the REST base URL. GitHub
+// Enterprise Server serves REST under /api/v3/ and GraphQL under /api/graphql,
+// while github.com and proxies serve GraphQL alongside REST.
+func graphQLURL(baseURL *url.URL) *url.URL {
+	if path, ok := strings.CutSuffix(baseURL.Path, "/api/v3/"); ok {
+		u := *baseURL
+		u.Path = path + "/api/graphql"
+		return &u
+	}
+	return baseURL.ResolveReference(&url.URL{Path: "graphql"})
+}
+
+func executeGraphQL(client *githubClient, ctx context.Context, query string, variables map[string]any, response any) error {
+	req := graphQLRequest{
+		Query:     query,
+		Variables: variables,
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(req); err != nil {
+		return err
+	}
+
+	graphqlReq, err := http.NewRequestWithContext(ctx, "POST", graphQLURL(client.BaseURL).String(), &buf)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Client().Do(graphqlReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return json.NewDecoder(resp.Body).Decode(response)
+}
+
+func updateForkSyncMetrics(ctx context.Context, client *githubClient, opts *collectorOptions, repo *github.Repository) error {
+	if !repo.GetFork() {
+		return nil
+	}
+
+	owner, repoName := repo.GetOwner().GetLogin(), repo.GetName()
+
+	// The repository listing omits the parent, so fetch the full repository.
+	fullRepo, _, err := client.Repositories.Get(ctx, owner, repoName)
+	if err != nil {
+		return err
+	}
+	parent := fullRepo.GetParent()
+	if parent == nil {
+		return nil
+	}
+
+	base := parent.GetOwner().GetLogin() + ":" + parent.GetDefaultBranch()
+	comparison, _, err := client.Repositories.CompareCommits(ctx, owner, repoName, base, repo.GetDefaultBranch(), &github.ListOptions{PerPage: 1})
+	if err != nil {
+		return err
+	}
+
+	labels := prometheus.Labels{
+		"github_repo": repo.GetFullName(),
+		"parent_repo": parent.GetFullName(),
+	}
+	forkAheadBy.With(labels).Set(float64(comparison.GetAheadBy()))
+	forkBehindBy.With(labels).Set(float64(comparison.GetBehindBy()))
+
+	return nil
+}
+
+// updateBranchProtectionMetrics reads protection over REST. Default branches
+// come from the shared repository query instead; see setRepositoryMetrics.
+func updateBranchProtectionMetrics(ctx context.Context, client *githubClient, opts *collectorOptions, repo *github.Repository) error {
+	branch := repo.GetDefaultBranch()
+	labels := prometheus.Labels{
+		"github_repo": repo.GetFullName(),
+		"branch":      branch,
+	}
+
+	protection, _, err := client.Repositories.GetBranchProtection(ctx, repo.GetOwner().GetLogin(), repo.GetName(), branch)
+	if errors.Is(err, github.ErrBranchNotProtected) {
+		setBranchProtectionMetrics(labels, false, 0, 0, false)
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	requiredReviews := 0
+	if reviews := protection.GetRequiredPullRequestReviews(); reviews != nil {
+		requiredReviews = reviews.RequiredApprovingReviewCount
+	}
+
+	requiredChecks := 0
+	if checks := protection.GetRequiredStatusChecks(); checks != nil {
+		if checks.Checks != nil {
+			requiredChecks = len(*checks.Checks)
+		} else if checks.Contexts != nil {
+			requiredChecks = len(*checks.Contexts)
+		}
+	}
+
+	enforceAdmins := protection.GetEnforceAdmins() != nil && protection.GetEnforceAdmins().Enabled
+
+	setBranchProtectionMetrics(labels, true, requiredReviews, requiredChecks, enforceAdmins)
+	return nil
+}
+
+func setBranchProtectionMetrics(labels prometheus.Labels, enabled bool, requiredReviews, requiredChecks int, enforceAdmins bool) {
+	branchProtectionEnabled.With(labels).Set(boolToFloat(enabled))
+	branchProtectionRequiredReviews.With(labels).Set(float64(requiredReviews))
+	branchProtectionRequiredStatusChecks.With(labels).Set(float64(requiredChecks))
+	branchProtectionEnforceAdmins.With(labels).Set(boolToFloat(enforceAdmins))
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func updateRulesetMetrics(ctx context.Context, client *githubClient, opts *collectorOptions, repo *github.Repository) error {
+	rulesets, _, err := client.Repositories.GetAllRulesets(ctx, repo.GetOwner().GetLogin(), repo.GetName(), true)
+	if err != nil {
+		return err
+	}
+
+	counts := map[string]int{"branch": 0, "tag": 0, "push": 0}
+	for _, ruleset := range rulesets {
+		if ruleset.Enforcement != "active" {
+			continue
+		}
+		// Rulesets created before targets were introduced only apply to branches.
+		target := "branch"
+		if ruleset.Target != nil {
+			target = *ruleset.Target
+		}
+		counts[target]++
+	}
+
+	for target, count := range counts {
+		rulesetCount.With(prometheus.Labels{
+			"github_repo": repo.GetFullName(),
+			"target":      target,
+		}).Set(float64(count))
+	}
+
+	return nil
+}
+
+func updateCommitStatusMetrics(ctx context.Context, client *githubClient, opts *collectorOptions, repo *github.Repository) error {
+	branch := repo.GetDefaultBranch()
+
+	status, _, err := client.Repositories.GetCombinedStatus(ctx, repo.GetOwner().GetLogin(), repo.GetName(), branch, nil)
+	if err != nil {
+		return err
+	}
+
+	// Commits without any statuses report "pending", which would be misleading,
+	// so the repository's series are dropped instead.
+	commitStatusState.DeletePartialMatch(prometheus.Labels{"github_repo": repo.GetFullName()})
+	if status.GetTotalCount() == 0 {
+		return nil
+	}
+
+	for _, state := range []string{"failure", "pending", "success"} {
+		commitStatusState.With(prometheus.Labels{
+			"github_repo": repo.GetFullName(),
+			"branch":      branch,
+			"state":       state,
+		}).Set(boolToFloat(state == status.GetState()))
+	}
+
+	return nil
+}
+
+func updateLatestReleaseMetrics(ctx context.Context, client *githubClient, opts *collectorOptions, repo *github.Repository) error {
+	release, resp, err := client.Repositories.GetLatestRelease(ctx, repo.GetOwner().GetLogin(), repo.GetName())
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	setLatestReleaseMetrics(repo.GetFullName(), release.GetTagName(), release.GetPublishedAt().Time)
+
+	return nil
+}
+
+func setLatestReleaseMetrics(fullName, tagName string, publishedAt time.Time) {
+	labels := prometheus.Labels{"github_repo": fullName}
+
+	releaseLatestTimestamp.With(labels).Set(float64(publishedAt.Unix()))
+	releaseLatestAgeDays.With(labels).Set(time.Since(publishedAt).Hours() / 24)
+
+	releaseLatestInfo.DeletePartialMatch(labels)
+	releaseLatestInfo.With(prometheus.Labels{
+		"github_repo": fullName,
+		"tag_name":    tagName,
+	}).Set(1)
+}
+
+func updateReleaseAssetMetrics(ctx context.Context, client *githubClient, opts *collectorOptions, repo *github.Repository) error {
+	releases, _, err := client.Repositories.ListReleases(ctx, repo.GetOwner().GetLogin(), repo.GetName(), &github.ListOptions{
+		PerPage: opts.ReleaseAssetReleases,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Releases that fall out of the window and assets that no longer match
+	// drop out with the rest of the repository's series.
+	releaseAssetDownloadCount.DeletePartialMatch(prometheus.Labels{"github_repo": repo.GetFullName()})
+	for _, release := range releases {
+		if release.GetDraft() {
+			continue
+		}
+		for _, asset := range release.Assets {
+			if opts.ReleaseAssetPattern != nil && !opts.ReleaseAssetPattern.MatchString(asset.GetName()) {
+				continue
+			}
+			releaseAssetDownloadCount.With(prometheus.Labels{
+				"github_repo": repo.GetFullName(),
+				"tag_name":    release.GetTagName(),
+				"asset_name":  asset.GetName(),
+			}).Set(float64(asset.GetDownloadCount()))
+		}
+	}
+
+	return nil
+}
+
+func updateReleaseCountMetrics(ctx context.Context, client *githubClient, opts *collectorOptions, repo *github.Repository) error {
+	listOpts := &github.ListOptions{PerPage: opts.PerPage}
+
+	counts := map[string]int{"draft": 0, "prerelease": 0, "release": 0}
+	for {
+		releases, resp, err := client.Repositories.ListReleases(ctx, repo.GetOwner().GetLogin(), repo.GetName(), listOpts)
+		if err != nil {
+			return err
+		}
+
+		for _, release := range releases {
+			switch {
+			case release.GetDraft():
+				counts["draft"]++
+			case release.GetPrerelease():
+				counts["prerelease"]++
+			default:
+				counts["release"]++
+			}
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		listOpts.Page = resp.NextPage
+	}
+
+	for releaseType, count := range counts {
+		releaseCount.With(prometheus.Labels{
+			"github_repo": repo.GetFullName(),
+			"type":        releaseType,
+		}).Set(float64(count))
+	}
+
+	return nil
+}
+
+func updateUnreleasedCommitMetrics(ctx context.Context, client *githubClient, opts *collectorOptions, repo *github.Repository) error {
+	owner, repoName := repo.GetOwner().GetLogin(), repo.GetName()
+
+	release, resp, err := client.Repositories.GetLatestRelease(ctx, owner, repoName)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	comparison, _, err := client.Repositories.CompareCommits(ctx, owner, repoName, release.GetTagName(), repo.GetDefaultBranch(), &github.ListOptions{PerPage: 1})
+	if err != nil {
+		return err
+	}
+
+	unreleasedCommitCount.With(prometheus.Labels{"github_repo": repo.GetFullName()}).Set(float64(comparison.GetAheadBy()))
+
+	return nil
+}
+
+var packageTypes = []string{"container", "docker", "maven", "npm", "nuget", "rubygems"}
+
+func updatePackageMetrics(ctx context.Context, client *githubClient, opts *collectorOptions) error {
+	user, _, err := client.Users.Get(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	// An empty org lists the authenticated user's packages.
+	for _, org := range append([]string{""}, opts.Orgs...) {
+		owner := org
+		if owner == "" {
+			owner = user.GetLogin()
+		}
+
+		for _, packageType := range packageTypes {
+			packages, err := fetchPackages(ctx, client, opts.PerPage, org, packageType)
+			if err != nil {
+				return fmt.Errorf("%s %s packages: %w", owner, packageType, err)
+			}
+
+			packageCount.With(prometheus.Labels{
+				"owner":        owner,
+				"package_type": packageType,
+			}).Set(float64(len(packages)))
+
+			for _, pkg := range packages {
+				packageVersionCount.With(prometheus.Labels{
+					"owner":        owner,
+					"package_type": packageType,
+					"package_name": pkg.GetName(),
+				}).Set(float64(pkg.GetVersionCount()))
+			}
+		}
+
+		if err := updatePackageDownloadMetrics(ctx, client, opts, owner); err != nil {
+			return fmt.Errorf("%s package downloads: %w", owner, err)
+		}
+	}
+
+	return nil
+}
+
+const packageDownloadsGraphQLQuery = `
+query($login: String!, $cursor: String, $perPage: Int!) {
+	repositoryOwner(login: $login) {
+		... on PackageOwner {
+			packages(first: $perPage, after: $cursor) {
+				nodes {
+					name
+					packageType
+					statistics { downloadsTotalCount }
+				}
+				pageInfo { hasNextPage endCursor }
+			}
+		}
+	}
+}`
+
+type graphQLPackageDownloadsResponse struct {
+	Data struct {
+		RepositoryOwner struct {
+			Packages struct {
+				Nodes []struct {
+					Name        string `json:"name"`
+					PackageType string `json:"packageType"`
+					Statistics  *struct {
+						DownloadsTotalCount int `json:"downloadsTotalCount"`
+					} `json:"statistics"`
+				} `json:"nodes"`
+				PageInfo graphQLPageInfo `json:"pageInfo"`
+			} `json:"packages"`
+		} `json:"repositoryOwner"`
+	} `json:"data"`
+}
+
+// updatePackageDownloadMetrics reads download counts from GraphQL, as the
+// REST packages API has none. GraphQL only covers the registries that have
+// not moved to the newer package service, so ghcr.io containers and npm
+// packages have no download series.
+func updatePackageDownloadMetrics(ctx context.Context, client *githubClient, opts *collectorOptions, owner string) error {
+	variables := map[string]any{"login": owner, "perPage": opts.PerPage}
+	for {
+		var response graphQLPackageDownloadsResponse
+		if err := executeGraphQL(client, ctx, packageDownloadsGraphQLQuery, variables, &response); err != nil {
+			return err
+		}
+
+		packages := response.Data.RepositoryOwner.Packages
+		for _, pkg := range packages.Nodes {
+			packageType := strings.ToLower(pkg.PackageType)
+			if pkg.Statistics == nil || !slices.Contains(packageTypes, packageType) {
+				continue
+			}
+			packageDownloadCount.With(prometheus.Labels{
+				"owner":        owner,
+				"package_type": packageType,
+				"package_name": pkg.Name,
+			}).Set(float64(pkg.Statistics.DownloadsTotalCount))
+		}
+
+		if !packages.PageInfo.HasNextPage {
+			return nil
+		}
+		variables["cursor"] = packages.PageInfo.EndCursor
+	}
+}
+
+func fetchPackages(ctx context.Context, client *githubClient, perPage int, org, packageType string) ([]*github.Package, error) {
+	opts := &github.PackageListOptions{
+		PackageType: github.Ptr(packageType),
+		ListOptions: github.ListOptions{
+			PerPage: perPage,
+		},
+	}
+
+	var allPackages []*github.Package
+	for {
+		var packages []*github.Package
+		var resp *github.Response
+		var err error
+		if org == "" {
+			packages, resp, err = client.Users.ListPackages(ctx, "", opts)
+		} else {
+			packages, resp, err = client.Organizations.ListPackages(ctx, org, opts)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		allPackages = append(allPackages, packages...)
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return allPackages, nil
+}
+
+func updateContainerVersionMetrics(ctx context.Context, client *githubClient, opts *collectorOptions) error {
+	user, _, err := client.Users.Get(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	for _, org := range append([]string{""}, opts.Orgs...) {
+		owner := org
+		if owner == "" {
+			owner = user.GetLogin()
+		}
+
+		packages, err := fetchPackages(ctx, client, opts.PerPage, org, "container")
+		if err != nil {
+			return fmt.Errorf("%s container packages: %w", owner, err)
+		}
+
+		for _, pkg := range packages {
+			versions, err := fetchPackageVersions(ctx, client, opts.PerPage, org, "container", pkg.GetName())
+			if err != nil {
+				return fmt.Errorf("%s versions: %w", pkg.GetName(), err)
+			}
+
+			var latest time.Time
+			untagged := 0
+			for _, version := range versions {
+				if created := version.GetCreatedAt().Time; created.After(latest) {
+					latest = created
+				}
+				if len(version.GetMetadata().GetContainer().Tags) == 0 {
+					untagged++
+				}
+			}
+
+			labels := prometheus.Labels{
+				"owner":        owner,
+				"package_name": pkg.GetName(),
+			}
+			if !latest.IsZero() {
+				packageLatestVersionAge.With(labels).Set(time.Since(latest).Seconds())
+			}
+			packageUntaggedVersionCount.With(labels).Set(float64(untagged))
+		}
+	}
+
+	return nil
+}
+
+func fetchPackageVersions(ctx context.Context, client *githubClient, perPage int, org, packageType, packageName string) ([]*github.PackageVersion, error) {
+	opts := &github.PackageListOptions{
+		ListOptions: github.ListOptions{
+			PerPage: perPage,
+		},
+	}
+
+	var allVersions []*github.PackageVersion
+	for {
+		var versions []*github.PackageVersion
+		var resp *github.Response
+		var err error
+		if org == "" {
+			versions, resp, err = client.Users.PackageGetAllVersions(ctx, "", packageType, packageName, opts)
+		} else {
+			versions, resp, err = client.Organizations.PackageGetAllVersions(ctx, org, packageType, packageName, opts)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		allVersions = append(allVersions, versions...)
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return allVersions, nil
+}
+
+func updatePagesMetrics(ctx context.Context, client *githubClient, opts *collectorOptions, repo *github.Repository) error {
+	labels := prometheus.Labels{"github_repo": repo.GetFullName()}
+
+	pagesEnabled.With(labels).Set(boolToFloat(repo.GetHasPages()))
+	if !repo.GetHasPages() {
+		return nil
+	}
+
+	build, resp, err := client.Repositories.GetLatestPagesBuild(ctx, repo.GetOwner().GetLogin(), repo.GetName())
+	// Sites deployed with a custom Actions workflow have no legacy builds.
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	for _, status := range []string{"building", "built", "errored"} {
+		pagesBuildStatus.With(prometheus.Labels{
+			"github_repo": repo.GetFullName(),
+			"status":      status,
+		}).Set(boolToFloat(status == build.GetStatus()))
+	}
+	pagesBuildTimestamp.With(labels).Set(float64(build.GetUpdatedAt().Unix()))
+
+	return nil
+}
+
+func updateDeployKeyMetrics(ctx context.Context, client *githubClient, opts *collectorOptions, repo *github.Repository) error {
+	keys, _, err := client.Repositories.ListKeys(ctx, repo.GetOwner().GetLogin(), repo.GetName(), &github.ListOptions{PerPage: 100})
+	if err != nil {
+		return err
+	}
+
+	labels := prometheus.Labels{"github_repo": repo.GetFullName()}
+	deployKeyCount.With(labels).Set(float64(len(keys)))
+
+	var oldest time.Time
+	for _, key := range keys {
+		if created := key.GetCreatedAt().Time; oldest.IsZero() || created.Before(oldest) {
+			oldest = created
+		}
+	}
+	if oldest.IsZero() {
+		deployKeyOldestAge.Delete(labels)
+	} else {
+		deployKeyOldestAge.With(labels).Set(time.Since(oldest).Seconds())
+	}
+
+	return nil
+}
+
+func updateWebhookMetrics(ctx context.Context, client *githubClient, opts *collectorOptions, repo *github.Repository) error {
+	owner, repoName := repo.GetOwner().GetLogin(), repo.GetName()
+
+	hooks, _, err := client.Repositories.ListHooks(ctx, owner, repoName, &github.ListOptions{PerPage: 100})
+	if err != nil {
+		return err
+	}
+
+	webhookCount.With(prometheus.Labels{"github_repo": repo.GetFullName()}).Set(float64(len(hooks)))
+
+	since := time.Now().Add(-opts.WebhookDeliveryWindow)
+	failedByHook := make(map[int64]int)
+	for _, hook := range hooks {
+		failed, err := countFailedDeliveries(since, opts.PerPage, func(listOpts *github.ListCursorOptions) ([]*github.HookDelivery, *github.Response, error) {
+			return client.Repositories.ListHookDeliveries(ctx, owner, repoName, hook.GetID(), listOpts)
+		})
+		if err != nil {
+			return fmt.Errorf("hook %d deliveries: %w", hook.GetID(), err)
+		}
+		failedByHook[hook.GetID()] = failed
+	}
+
+	// Deleted hooks drop out with the rest of the repository's series.
+	webhookFailedDeliveries.DeletePartialMatch(prometheus.Labels{"github_repo": repo.GetFullName()})
+	for id, failed := range failedByHook {
+		webhookFailedDeliveries.With(prometheus.Labels{
+			"github_repo": repo.GetFullName(),
+			"hook_id":     fmt.Sprintf("%d", id),
+		}).Set(float64(failed))
+	}
+
+	return nil
+}
+
+// countFailedDeliveries pages through hook deliveries, newest first, counting
+// those delivered after since that did not receive a 2xx response.
+func countFailedDeliveries(since time.Time, perPage int, list func(*github.ListCursorOptions) ([]*github.HookDelivery, *github.Response, error)) (int, error) {
+	listOpts := &github.ListCursorOptions{PerPage: perPage}
+
+	failed := 0
+	for {
+		deliveries, resp, err := list(listOpts)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, delivery := range deliveries {
+			if delivery.GetDeliveredAt().Before(since) {
+				return failed, nil
+			}
+			if code := delivery.GetStatusCode(); code < 200 || code >= 300 {
+				failed++
+			}
+		}
+
+		if resp.Cursor == "" {
+			return failed, nil
+		}
+		listOpts.Cursor = resp.Cursor
+	}
+}
+
+func updateOrgWebhookMetrics(ctx context.Context, client *githubClient, opts *collectorOptions) error {
+	since := time.Now().Add(-opts.WebhookDeliveryWindow)
+
+	for _, org := range opts.Orgs {
+		hooks, _, err := client.Organizations.ListHooks(ctx, org, &github.ListOptions{PerPage: 100})
+		if err != nil {
+			return fmt.Errorf("%s hooks: %w", org, err)
+		}
+
+		failedByHook := make(map[int64]int)
+		for _, hook := range hooks {
+			if !hook.GetActive() {
+				continue
+			}
+
+			failed, err := countFailedDeliveries(since, opts.PerPage, func(listOpts *github.ListCursorOptions) ([]*github.HookDelivery, *github.Response, error) {
+				return client.Organizations.ListHookDeliveries(ctx, org, hook.GetID(), listOpts)
+			})
+			if err != nil {
+				return fmt.Errorf("%s hook %d deliveries: %w", org, hook.GetID(), err)
+			}
+			failedByHook[hook.GetID()] = failed
+		}
+
+		// Deleted and deactivated hooks drop out with the rest of the
+		// organization's series.
+		orgWebhookFailedDeliveries.DeletePartialMatch(prometheus.Labels{"org": org})
+		for id, failed := range failedByHook {
+			orgWebhookFailedDeliveries.With(prometheus.Labels{
+				"org":     org,
+				"hook_id": fmt.Sprintf("%d", id),
+			}).Set(float64(failed))
+		}
+
+		orgWebhookCount.With(prometheus.Labels{"org": org}).Set(float64(len(failedByHook)))
+	}
+
+	return nil
+}
+
+// staleIssuesGraphQLQuery lists open issues or pull requests, least recently
+// updated first. The %s verb is replaced with the connection name.
+const staleIssuesGraphQLQuery = `
+query($owner: String!, $name: String!, $cursor: String, $perPage: Int!) {
+	repository(owner: $owner, name: $name) {
+		items: %s(states: OPEN, first: $perPage, after: $cursor, orderBy: {field: UPDATED_AT, direction: ASC}) {
+			nodes { updatedAt }
+			pageInfo { hasNextPage endCursor }
+		}
+	}
+}`
+
+type graphQLStaleIssuesResponse struct {
+	Data struct {
+		Repository struct {
+			Items struct {
+				Nodes []struct {
+					UpdatedAt time.Time `json:"updatedAt"`
+				} `json:"nodes"`
+				PageInfo graphQLPageInfo `json:"pageInfo"`
+			} `json:"items"`
+		} `json:"repository"`
+	} `json:"data"`
+}
+
+func updateStaleIssueMetrics(ctx context.Context, client *githubClient, opts *collectorOptions, repo *github.Repository) error {
+	cutoff := time.Now().Add(-opts.StaleIssueAge)
+
+	for issueType, connection := range map[string]string{"issue": "issues", "pull": "pullRequests"} {
+		variables := map[string]any{
+			"owner":   repo.GetOwner().GetLogin(),
+			"name":    repo.GetName(),
+			"perPage": opts.PerPage,
+		}
+		query := fmt.Sprintf(staleIssuesGraphQLQuery, connection)
+
+		stale := 0
+	pages:
+		for {
+			var response graphQLStaleIssuesResponse
+			if err := executeGraphQL(client, ctx, query, variables, &response); err != nil {
+				return err
+			}
+
+			items := response.Data.Repository.Items
+			for _, item := range items.Nodes {
+				if !item.UpdatedAt.Before(cutoff) {
+					break pages
+				}
+				stale++
+			}
+
+			if !items.PageInfo.HasNextPage {
+				break
+			}
+			variables["cursor"] = items.PageInfo.EndCursor
+		}
+
+		staleIssueCount.With(prometheus.Labels{
+			"github_repo": repo.GetFullName(),
+			"type":        issueType,
+		}).Set(float64(stale))
+	}
+
+	return nil
+}
+
+const issueAssigneesGraphQLQuery = `
+query($owner: String!, $name: String!, $cursor: String, $perPage: Int!) {
+	repository(owner: $owner, name: $name) {
+		issues(states: OPEN, first: $perPage, after: $cursor) {
+			nodes {
+				assignees(first: 10) { nodes { login } }
+			}
+			pageInfo { hasNextPage endCursor }
+		}
+	}
+}`
+
+type graphQLIssueAssigneesResponse struct {
+	Data struct {
+		Repository struct {
+			Issues struct {
+				Nodes []struct {
+					Assignees struct {
+						Nodes []struct {
+							Login string `json:"login"`
+						} `json:"nodes"`
+					} `json:"assignees"`
+				} `json:"nodes"`
+				PageInfo graphQLPageInfo `json:"pageInfo"`
+			} `json:"issues"`
+		} `json:"repository"`
+	} `json:"data"`
+}
+
+func updateIssueAssigneeMetrics(ctx context.Context, client *githubClient, opts *collectorOptions, repo *github.Repository) error {
+	variables := map[string]any{
+		"owner":   repo.GetOwner().GetLogin(),
+		"name":    repo.GetName(),
+		"perPage": opts.PerPage,
+	}
+
+	counts := make(map[string]int)
+	for {
+		var response graphQLIssueAssigneesResponse
+		if err := executeGraphQL(client, ctx, issueAssigneesGraphQLQuery, variables, &response); err != nil {
+			return err
+		}
+
+		issues := response.Data.Repository.Issues
+		for _, issue := range issues.Nodes {
+			if len(issue.Assignees.Nodes) == 0 {
+				counts["unassigned"]++
+			}
+			for _, assignee := range issue.Assignees.Nodes {
+				counts[assignee.Login]++
+			}
+		}
+
+		if !issues.PageInfo.HasNextPage {
+			break
+		}
+		variables["cursor"] = issues.PageInfo.EndCursor
+	}
+
+	issueAssigneeCount.DeletePartialMatch(prometheus.Labels{"github_repo": repo.GetFullName()})
+	for assignee, count := range capLabelCounts(counts, opts.AssigneeLimit, "unassigned") {
+		issueAssigneeCount.With(prometheus.Labels{
+			"github_repo": repo.GetFullName(),
+			"assignee":    assignee,
+		}).Set(float64(count))
+	}
+
+	return nil
+}
+
+// capLabelCounts keeps the limit largest counts, always including keep, and
+// sums the remainder into "other".
+func capLabelCounts(counts map[string]int, limit int, keep string) map[string]int {
+	if len(counts) <= limit {
+		return counts
+	}
+
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		if name != keep {
+			names = append(names, name)
+		}
+	}
+	slices.SortFunc(names, func(a, b string) int {
+		if counts[a] != counts[b] {
+			return counts[b] - counts[a]
+		}
+		return strings.Compare(a, b)
+	})
+
+	capped := make(map[string]int)
+	if count, ok := counts[keep]; ok {
+		capped[keep] = count
+	}
+	for i, name := range names {
+		if i < limit {
+			capped[name] = counts[name]
+		} else {
+			capped["other"] += counts[name]
+		}
+	}
+	return capped
+}
+
+const firstResponseGraphQLQuery = `
+query($owner: String!, $name: String!, $cursor: String, $perPage: Int!) {
+	repository(owner: $owner, name: $name) {
+		issues(first: $perPage, after: $cursor, orderBy: {field: CREATED_AT, direction: DESC}) {
+			nodes {
+				createdAt
+				author { login }
+				comments(first: 20) {
+					nodes {
+						createdAt
+						author { login }
+					}
+				}
+			}
+			pageInfo { hasNextPage endCursor }
+		}
+	}
+}`
+
+type graphQLFirstResponseResponse struct {
+	Data struct {
+		Repository struct {
+			Issues struct {
+				Nodes []struct {
+					CreatedAt time.Time     `json:"createdAt"`
+					Author    graphQLAuthor `json:"author"`
+					Comments  struct {
+						Nodes []struct {
+							CreatedAt time.Time     `json:"createdAt"`
+							Author    graphQLAuthor `json:"author"`
+						} `json:"nodes"`
+					} `json:"comments"`
+				} `json:"nodes"`
+				PageInfo graphQLPageInfo `json:"pageInfo"`
+			} `json:"issues"`
+		} `json:"repository"`
+	} `json:"data"`
+}
+
+type graphQLAuthor struct {
+	Login string `json:"login"`
+}
+
+func updateFirstResponseMetrics(ctx context.Context, client *githubClient, opts *collectorOptions, repo *github.Repository) error {
+	variables := map[string]any{
+		"owner":   repo.GetOwner().GetLogin(),
+		"name":    repo.GetName(),
+		"perPage": min(opts.PerPage, 50),
+	}
+
+	since := time.Now().Add(-opts.ResponseWindow)
+	var responseTimes []float64
+pages:
+	for {
+		var response graphQLFirstResponseResponse
+		if err := executeGraphQL(client, ctx, firstResponseGraphQLQuery, variables, &response); err != nil {
+			return err
+		}
+
+		issues := response.Data.Repository.Issues
+		for _, issue := range issues.Nodes {
+			if issue.CreatedAt.Before(since) {
+				break pages
+			}
+			for _, comment := range issue.Comments.Nodes {
+				if comment.Author.Login != issue.Author.Login {
+					responseTimes = append(responseTimes, comment.CreatedAt.Sub(issue.CreatedAt).Seconds())
+					break
+				}
+			}
+		}
+
+		if !issues.PageInfo.HasNextPage {
+			break
+		}
+		variables["cursor"] = issues.PageInfo.EndCursor
+	}
+
+	setQuantiles(issueFirstResponse, repo.GetFullName(), responseTimes)
+
+	return nil
+}
+
+// setQuantiles exports the median and p90 of values for a repository,
+// removing stale quantiles when there are no values.
+func setQuantiles(vec *prometheus.GaugeVec, repo string, values []float64) {
+	for _, q := range []float64{0.5, 0.9} {
+		labels := prometheus.Labels{
+			"github_repo": repo,
+			"quantile":    fmt.Sprint(q),
+		}
+		if len(values) == 0 {
+			vec.Delete(labels)
+			continue
+		}
+		vec.With(labels).Set(quantile(values, q))
+	}
+}
+
+// quantile returns the nearest-rank q-quantile of values, sorting it in place.
+func quantile(values []float64, q float64) float64 {
+	slices.Sort(values)
+	i := int(math.Ceil(q*float64(len(values)))) - 1
+	return values[max(i, 0)]
+}
+
+const issueRatesGraphQLQuery = `
+query($opened1d: String!, $opened7d: String!, $closed1d: String!, $closed7d: String!) {
+	opened1d: search(query: $opened1d, type: ISSUE) { issueCount }
+	opened7d: search(query: $opened7d, type: ISSUE) { issueCount }
+	closed1d: search(query: $closed1d, type: ISSUE) { issueCount }
+	closed7d: search(query: $closed7d, type: ISSUE) { issueCount }
+}`
+
+type graphQLIssueRatesResponse struct {
+	Data struct {
+		Opened1d graphQLSearchCount `json:"opened1d"`
+		Opened7d graphQLSearchCount `json:"opened7d"`
+		Closed1d graphQLSearchCount `json:"closed1d"`
+		Closed7d graphQLSearchCount `json:"closed7d"`
+	} `json:"data"`
+}
+
+type graphQLSearchCount struct {
+	IssueCount int `json:"issueCount"`
+}
+
+func updateIssueRateMetrics(ctx context.Context, client *githubClient, opts *collectorOptions, repo *github.Repository) error {
+	now := time.Now().UTC()
+	day := now.Add(-24 * time.Hour).Format(time.RFC3339)
+	week := now.Add(-7 * 24 * time.Hour).Format(time.RFC3339)
+	prefix := "repo:" + repo.GetFullName() + " is:issue "
+
+	variables := map[string]any{
+		"opened1d": prefix + "created:>=" + day,
+		"opened7d": prefix + "created:>=" + week,
+		"closed1d": prefix + "closed:>=" + day,
+		"closed7d": prefix + "closed:>=" + week,
+	}
+
+	var response graphQLIssueRatesResponse
+	if err := executeGraphQL(client, ctx, issueRatesGraphQLQuery, variables, &response); err != nil {
+		return err
+	}
+
+	data := response.Data
+	issueOpenedCount.With(prometheus.Labels{"github_repo": repo.GetFullName(), "window": "24h"}).Set(float64(data.Opened1d.IssueCount))
+	issueOpenedCount.With(prometheus.Labels{"github_repo": repo.GetFullName(), "window": "7d"}).Set(float64(data.Opened7d.IssueCount))
+	issueClosedCount.With(prometheus.Labels{"github_repo": repo.GetFullName(), "window": "24h"}).Set(float64(data.Closed1d.IssueCount))
+	issueClosedCount.With(prometheus.Labels{"github_repo": repo.GetFullName(), "window": "7d"}).Set(float64(data.Closed7d.IssueCount))
+
+	return nil
+}
+
+const pullReviewsGraphQLQuery = `
+query($owner: String!, $name: String!, $cursor: String, $perPage: Int!) {
+	repository(owner: $owner, name: $name) {
+		pullRequests(states: OPEN, first: $perPage, after: $cursor) {
+			nodes { reviewDecision }
+			pageInfo { hasNextPage endCursor }
+		}
+	}
+}`
+
+type graphQLPullReviewsResponse struct {
+	Data struct {
+		Repository struct {
+			PullRequests struct {
+				Nodes []struct {
+					ReviewDecision *string `json:"reviewDecision"`
+				} `json:"nodes"`
+				PageInfo graphQLPageInfo `json:"pageInfo"`
+			} `json:"pullRequests"`
+		} `json:"repository"`
+	} `json:"data"`
+}
+
+func updatePullReviewMetrics(ctx context.Context, client *githubClient, opts *collectorOptions, repo *github.Repository) error {
+	variables := map[string]any{
+		"owner":   repo.GetOwner().GetLogin(),
+		"name":    repo.GetName(),
+		"perPage": opts.PerPage,
+	}
+
+	counts := map[string]int{"approved": 0, "changes_requested": 0, "review_required": 0, "none": 0}
+	for {
+		var response graphQLPullReviewsResponse
+		if err := executeGraphQL(client, ctx, pullReviewsGraphQLQuery, variables, &response); err != nil {
+			return err
+		}
+
+		pulls := response.Data.Repository.PullRequests
+		for _, pull := range pulls.Nodes {
+			// The decision is null when the branch does not require reviews.
+			decision := "none"
+			if pull.ReviewDecision != nil {
+				decision = strings.ToLower(*pull.ReviewDecision)
+			}
+			counts[decision]++
+		}
+
+		if !pulls.PageInfo.HasNextPage {
+			break
+		}
+		variables["cursor"] = pulls.PageInfo.EndCursor
+	}
+
+	for decision, count := range counts {
+		pullReviewDecisionCount.With(prometheus.Labels{
+			"github_repo":     repo.GetFullName(),
+			"review_decision": decision,
+		}).Set(float64(count))
+	}
+
+	return nil
+}
+
+const searchCountGraphQLQuery = `
+query($query: String!) {
+	search(query: $query, type: ISSUE) { issueCount }
+}`
+
+type graphQLSearchCountResponse struct {
+	Data struct {
+		Search graphQLSearchCount `json:"search"`
+	} `json:"data"`
+}
+
+func updateDraftPullMetrics(ctx context.Context, client *githubClient, opts *collectorOptions, repo *github.Repository) error {
+	variables := map[string]any{
+		"query": "repo:" + repo.GetFullName() + " is:pr is:open draft:true",
+	}
+
+	var response graphQLSearchCountResponse
+	if err := executeGraphQL(client, ctx, searchCountGraphQLQuery, variables, &response); err != nil {
+		return err
+	}
+
+	pullDraftCount.With(prometheus.Labels{"github_repo": repo.GetFullName()}).Set(float64(response.Data.Search.IssueCount))
+
+	return nil
+}
+
+const mergedPullsGraphQLQuery = `
+query($owner: String!, $name: String!, $cursor: String, $perPage: Int!) {
+	repository(owner: $owner, name: $name) {
+		pullRequests(states: MERGED, first: $perPage, after: $cursor, orderBy: {field: UPDATED_AT, direction: DESC}) {
+			nodes {
+				createdAt
+				updatedAt
+				mergedAt
+				additions
+				deletions
+				changedFiles
+			}
+			pageInfo { hasNextPage endCursor }
+		}
+	}
+}`
+
+type graphQLMergedPull struct {
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+	MergedAt     time.Time `json:"mergedAt"`
+	Additions    int       `json:"additions"`
+	Deletions    int       `json:"deletions"`
+	ChangedFiles int       `json:"changedFiles"`
+}
+
+type graphQLMergedPullsResponse struct {
+	Data struct {
+		Repository struct {
+			PullRequests struct {
+				Nodes    []graphQLMergedPull `json:"nodes"`
+				PageInfo graphQLPageInfo     `json:"pageInfo"`
+			} `json:"pullRequests"`
+		} `json:"repository"`
+	} `json:"data"`
+}
+
+// fetchMergedPulls returns pulls merged after since.
+func fetchMergedPulls(ctx context.Context, client *githubClient, perPage int, repo *github.Repository, since time.Time) ([]graphQLMergedPull, error) {
+	variables := map[string]any{
+		"owner":   repo.GetOwner().GetLogin(),
+		"name":    repo.GetName(),
+		"perPage": perPage,
+	}
+
+	var merged []graphQLMergedPull
+	for {
+		var response graphQLMergedPullsResponse
+		if err := executeGraphQL(client, ctx, mergedPullsGraphQLQuery, variables, &response); err != nil {
+			return nil, err
+		}
+
+		pulls := response.Data.Repository.PullRequests
+		for _, pull := range pulls.Nodes {
+			// A pull is always updated when merged, so nothing older can match.
+			if pull.UpdatedAt.Before(since) {
+				return merged, nil
+			}
+			if pull.MergedAt.After(since) {
+				merged = append(merged, pull)
+			}
+		}
+
+		if !pulls.PageInfo.HasNextPage {
+			return merged, nil
+		}
+		variables["cursor"] = pulls.PageInfo.EndCursor
+	}
+}
+
+// mergedPullCache shares each repository's merged pulls between the
+// collectors that use them, so they are fetched once per cycle.
+var mergedPullCache = struct {
+	sync.Mutex
+	entries map[string]*mergedPullEntry
+}{entries: make(map[string]*mergedPullEntry)}
+
+type mergedPullEntry struct {
+	once  sync.Once
+	pulls []graphQLMergedPull
+	err   error
+}
+
+// resetMergedPullCache forgets the merged pulls fetched in the last cycle.
+func resetMergedPullCache() {
+	mergedPullCache.Lock()
+	defer mergedPullCache.Unlock()
+	mergedPullCache.entries = make(map[string]*mergedPullEntry)
+}
+
+// cachedMergedPulls returns pulls merged after since, fetching them at most
+// once per repository per cycle, as far back as any enabled collector needs.
+func cachedMergedPulls(ctx context.Context, client *githubClient, opts *collectorOptions, repo *github.Repository, since time.Time) ([]graphQLMergedPull, error) {
+	mergedPullCache.Lock()
+	entry, ok := mergedPullCache.entries[repo.GetFullName()]
+	if !ok {
+		entry = &mergedPullEntry{}
+		mergedPullCache.entries[repo.GetFullName()] = entry
+	}
+	mergedPullCache.Unlock()
+
+	entry.once.Do(func() {
+		window := opts.MergeWindow
+		if opts.enabled("dora") {
+			window = max(window, opts.DORAWindow)
+		}
+		entry.pulls, entry.err = fetchMergedPulls(ctx, client, opts.PerPage, repo, time.Now().Add(-window))
+	})
+	if entry.err != nil {
+		return nil, entry.err
+	}
+
+	var pulls []graphQLMergedPull
+	for _, pull := range entry.pulls {
+		if pull.MergedAt.After(since) {
+			pulls = append(pulls, pull)
+		}
+	}
+	return pulls, nil
+}
+
+func updateTimeToMergeMetrics(ctx context.Context, client *githubClient, opts *collectorOptions, repo *github.Repository) error {
+	pulls, err := cachedMergedPulls(ctx, client, opts, repo, time.Now().Add(-opts.MergeWindow))
+	if err != nil {
+		return err
+	}
+
+	durations := make([]float64, 0, len(pulls))
+	for _, pull := range pulls {
+		durations = append(durations, pull.MergedAt.Sub(pull.CreatedAt).Seconds())
+	}
+
+	setQuantiles(pullTimeToMerge, repo.GetFullName(), durations)
+
+	return nil
+}
+
+func updatePullSizeMetrics(ctx context.Context, client *githubClient, opts *collectorOptions, repo *github.Repository) error {
+	pulls, err := cachedMergedPulls(ctx, client, opts, repo, time.Now().Add(-opts.MergeWindow))
+	if err != nil {
+		return err
+	}
+
+	lines := make([]float64, 0, len(pulls))
+	files := make([]float64, 0, len(pulls))
+	for _, pull := range pulls {
+		lines = append(lines, float64(pull.Additions+pull.Deletions))
+		files = append(files, float64(pull.ChangedFiles))
+	}
+
+	setQuantiles(pullSizeLines, repo.GetFullName(), lines)
+	setQuantiles(pullChangedFiles, repo.GetFullName(), files)
+
+	return nil
+}
+
+func updateReviewRequestMetrics(ctx context.Context, client *githubClient, opts *collectorOptions) error {
+	variables := map[string]any{
+		"query": "is:pr is:open archived:false review-requested:@me",
+	}
+
+	var response graphQLSearchCountResponse
+	if err := executeGraphQL(client, ctx, searchCountGraphQLQuery, variables, &response); err != nil {
+		return err
+	}
+
+	pullReviewRequestedCount.Set(float64(response.Data.Search.IssueCount))
+
+	return nil
+}
+
+func updateAuthoredPullMetrics(ctx context.Context, client *githubClient, opts *collectorOptions) error {
+	const query = "is:pr is:open archived:false author:@me"
+
+	if !opts.AuthoredPullsByOwner {
+		result, _, err := client.Search.Issues(ctx, query, &github.SearchOptions{ListOptions: github.ListOptions{PerPage: 1}})
+		if err != nil {
+			return err
+		}
+		pullAuthoredOpenCount.Set(float64(result.GetTotal()))
+		return nil
+	}
+
+	searchOpts := &github.SearchOptions{ListOptions: github.ListOptions{PerPage: opts.PerPage}}
+	counts := make(map[string]int)
+	total := 0
+	for {
+		result, resp, err := client.Search.Issues(ctx, query, searchOpts)
+		if err != nil {
+			return err
+		}
+
+		total = result.GetTotal()
+		for _, issue := range result.Issues {
+			counts[repositoryURLOwner(issue.GetRepositoryURL())]++
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		searchOpts.Page = resp.NextPage
+	}
+
+	pullAuthoredOpenCount.Set(float64(total))
+	pullAuthoredOpenByOwnerCount.Reset()
+	for owner, count := range counts {
+		pullAuthoredOpenByOwnerCount.With(prometheus.Labels{"owner": owner}).Set(float64(count))
+	}
+
+	return nil
+}
+
+// repositoryURLOwner returns the owner from an API repository URL such as
+// https://api.github.com/repos/OWNER/REPO.
+func repositoryURLOwner(repoURL string) string {
+	parts := strings.Split(repoURL, "/")
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[len(parts)-2]
+}
+
+func updateAssignedIssueMetrics(ctx context.Context, client *githubClient, opts *collectorOptions) error {
+	result, _, err := client.Search.Issues(ctx, "is:issue is:open archived:false assignee:@me", &github.SearchOptions{
+		Sort:        "created",
+		Order:       "asc",
+		ListOptions: github.ListOptions{PerPage: 1},
+	})
+	if err != nil {
+		return err
+	}
+
+	issueAssignedOpenCount.Set(float64(result.GetTotal()))
+	if len(result.Issues) > 0 {
+		issueAssignedOldestAge.WithLabelValues().Set(time.Since(result.Issues[0].GetCreatedAt().Time).Seconds())
+	} else {
+		issueAssignedOldestAge.DeleteLabelValues()
+	}
+
+	return nil
+}
+
+const oldestSearchGraphQLQuery = `
+query($query: String!) {
+	search(query: $query, type: ISSUE, first: 1) {
+		issueCount
+		nodes {
+			... on Issue { createdAt }
+			... on PullRequest { createdAt }
+		}
+	}
+}`
+
+type graphQLOldestSearchResponse struct {
+	Data struct {
+		Search struct {
+			IssueCount int `json:"issueCount"`
+			graphQLCreatedAtNodes
+		} `json:"search"`
+	} `json:"data"`
+}
+
+var defaultBotAuthors = []string{"dependabot[bot]"}
+
+func updateBotPullMetrics(ctx context.Context, client *githubClient, opts *collectorOptions, repo *github.Repository) error {
+	authors := opts.BotAuthors
+	if len(authors) == 0 {
+		authors = defaultBotAuthors
+	}
+
+	for _, author := range authors {
+		// App accounts are searched as app/NAME rather than NAME[bot].
+		qualifier := author
+		if name, ok := strings.CutSuffix(author, "[bot]"); ok {
+			qualifier = "app/" + name
+		}
+
+		variables := map[string]any{
+			"query": "repo:" + repo.GetFullName() + " is:pr is:open author:" + qualifier + " sort:created-asc",
+		}
+
+		var response graphQLOldestSearchResponse
+		if err := executeGraphQL(client, ctx, oldestSearchGraphQLQuery, variables, &response); err != nil {
+			return fmt.Errorf("%s: %w", author, err)
+		}
+
+		labels := prometheus.Labels{
+			"github_repo": repo.GetFullName(),
+			"author":      author,
+		}
+		search := response.Data.Search
+		pullBotOpenCount.With(labels).Set(float64(search.IssueCount))
+		if len(search.Nodes) == 0 {
+			pullBotOldestAge.Delete(labels)
+		} else {
+			pullBotOldestAge.With(labels).Set(time.Since(search.Nodes[0].CreatedAt).Seconds())
+		}
+	}
+
+	return nil
+}
+
+const mergeQueueGraphQLQuery = `
+query($owner: String!, $name: String!, $branch: String!) {
+	repository(owner: $owner, name: $name) {
+		mergeQueue(branch: $branch) {
+			entries(first: 100) {
+				totalCount
+				nodes { enqueuedAt }
+			}
+		}
+	}
+}`
+
+type graphQLMergeQueueResponse struct {
+	Data struct {
+		Repository struct {
+			MergeQueue *struct {
+				Entries struct {
+					TotalCount int `json:"totalCount"`
+					Nodes      []struct {
+						EnqueuedAt time.Time `json:"enqueuedAt"`
+					} `json:"nodes"`
+				} `json:"entries"`
+			} `json:"mergeQueue"`
+		} `json:"repository"`
+	} `json:"data"`
+}
+
+func updateMergeQueueMetrics(ctx context.Context, client *githubClient, opts *collectorOptions, repo *github.Repository) error {
+	branch := repo.GetDefaultBranch()
+	variables := map[string]any{
+		"owner":  repo.GetOwner().GetLogin(),
+		"name":   repo.GetName(),
+		"branch": branch,
+	}
+
+	var response graphQLMergeQueueResponse
+	if err := executeGraphQL(client, ctx, mergeQueueGraphQLQuery, variables, &response); err != nil {
+		return err
+	}
+
+	// Branches without a merge queue return null.
+	queue := response.Data.Repository.MergeQueue
+	if queue == nil {
+		return nil
+	}
+
+	labels := prometheus.Labels{
+		"github_repo": repo.GetFullName(),
+		"branch":      branch,
+	}
+	mergeQueueDepth.With(labels).Set(float64(queue.Entries.TotalCount))
+
+	var oldest time.Time
+	for _, entry := range queue.Entries.Nodes {
+		if oldest.IsZero() || entry.EnqueuedAt.Before(oldest) {
+			oldest = entry.EnqueuedAt
+		}
+	}
+	if oldest.IsZero() {
+		mergeQueueOldestAge.Delete(labels)
+	} else {
+		mergeQueueOldestAge.With(labels).Set(time.Since(oldest).Seconds())
+	}
+
+	return nil
+}
+
+func updateMilestoneMetrics(ctx context.Context, client *githubClient, opts *collectorOptions, repo *github.Repository) error {
+	milestones, _, err := client.Issues.ListMilestones(ctx, repo.GetOwner().GetLogin(), repo.GetName(), &github.MilestoneListOptions{
+		State:       "open",
+		ListOptions: github.ListOptions{PerPage: 100},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Drop milestones closed since the last update.
+	repoLabels := prometheus.Labels{"github_repo": repo.GetFullName()}
+	milestoneIssueCount.DeletePartialMatch(repoLabels)
+	milestoneDueTimestamp.DeletePartialMatch(repoLabels)
+
+	for _, milestone := range milestones {
+		milestoneIssueCount.With(prometheus.Labels{
+			"github_repo": repo.GetFullName(),
+			"milestone":   milestone.GetTitle(),
+			"state":       "open",
+		}).Set(float64(milestone.GetOpenIssues()))
+
+		milestoneIssueCount.With(prometheus.Labels{
+			"github_repo": repo.GetFullName(),
+			"milestone":   milestone.GetTitle(),
+			"state":       "closed",
+		}).Set(float64(milestone.GetClosedIssues()))
+
+		if milestone.DueOn != nil {
+			milestoneDueTimestamp.With(prometheus.Labels{
+				"github_repo": repo.GetFullName(),
+				"milestone":   milestone.GetTitle(),
+			}).Set(float64(milestone.GetDueOn().Unix()))
+		}
+	}
+
+	return nil
+}
+
+const discussionsGraphQLQuery = `
+query($owner: String!, $name: String!) {
+	repository(owner: $owner, name: $name) {
+		open: discussions(states: [OPEN]) { totalCount }
+		closed: discussions(states: [CLOSED]) { totalCount }
+		discussionCategories(first: 100) {
+			nodes {
+				id
+				isAnswerable
+			}
+		}
+	}
+}`
+
+type graphQLDiscussionsResponse struct {
+	Data struct {
+		Repository struct {
+			Open                 graphQLTotalCount `json:"open"`
+			Closed               graphQLTotalCount `json:"closed"`
+			DiscussionCategories struct {
+				Nodes []struct {
+					ID           string `json:"id"`
+					IsAnswerable bool   `json:"isAnswerable"`
+				} `json:"nodes"`
+			} `json:"discussionCategories"`
+		} `json:"repository"`
+	} `json:"data"`
+}
+
+// answeredDiscussionsGraphQLQuery counts open discussions by whether they are
+// answered in each answerable category, as the answered filter alone also
+// matches discussions in categories that cannot be answered. The %s verb is
+// replaced with aliased fields from answeredDiscussionsGraphQLFields.
+const answeredDiscussionsGraphQLQuery = `
+query($owner: String!, $name: String!) {
+	repository(owner: $owner, name: $name) {
+%s	}
+}`
+
+func answeredDiscussionsGraphQLFields(categoryIDs []string) string {
+	var b strings.Builder
+	for i, id := range categoryIDs {
+		// A JSON string is also a valid GraphQL string literal.
+		quoted, _ := json.Marshal(id)
+		fmt.Fprintf(&b, "\t\tanswered%d: discussions(states: [OPEN], categoryId: %s, answered: true) { totalCount }\n", i, quoted)
+		fmt.Fprintf(&b, "\t\tunanswered%d: discussions(states: [OPEN], categoryId: %s, answered: false) { totalCount }\n", i, quoted)
+	}
+	return b.String()
+}
+
+type graphQLAnsweredDiscussionsResponse struct {
+	Data struct {
+		Repository map[string]graphQLTotalCount `json:"repository"`
+	} `json:"data"`
+}
+
+type graphQLTotalCount struct {
+	TotalCount int `json:"totalCount"`
+}
+
+func updateDiscussionMetrics(ctx context.Context, client *githubClient, opts *collectorOptions, repo *github.Repository) error {
+	if !repo.GetHasDiscussions() {
+		return nil
+	}
+
+	variables := map[string]any{
+		"owner": repo.GetOwner().GetLogin(),
+		"name":  repo.GetName(),
+	}
+
+	var response graphQLDiscussionsResponse
+	if err := executeGraphQL(client, ctx, discussionsGraphQLQuery, variables, &response); err != nil {
+		return err
+	}
+
+	data := response.Data.Repository
+	discussionCount.With(prometheus.Labels{"github_repo": repo.GetFullName(), "state": "open"}).Set(float64(data.Open.TotalCount))
+	discussionCount.With(prometheus.Labels{"github_repo": repo.GetFullName(), "state": "closed"}).Set(float64(data.Closed.TotalCount))
+
+	var categoryIDs []string
+	for _, category := range data.DiscussionCategories.Nodes {
+		if category.IsAnswerable {
+			categoryIDs = append(categoryIDs, category.ID)
+		}
+	}
+
+	answered, unanswered := 0, 0
+	if len(categoryIDs) > 0 {
+		var answeredResponse graphQLAnsweredDiscussionsResponse
+		query := fmt.Sprintf(answeredDiscussionsGraphQLQuery, answeredDiscussionsGraphQLFields(categoryIDs))
+		if err := executeGraphQL(client, ctx, query, variables, &answeredResponse); err != nil {
+			return err
+		}
+		for i := range categoryIDs {
+			answered += answeredResponse.Data.Repository[fmt.Sprintf("answered%d", i)].TotalCount
+			unanswered += answeredResponse.Data.Repository[fmt.Sprintf("unanswered%d", i)].TotalCount
+		}
+	}
+	discussionAnsweredCount.With(prometheus.Labels{"github_repo": repo.GetFullName(), "answered": "true"}).Set(float64(answered))
+	discussionAnsweredCount.With(prometheus.Labels{"github_repo": repo.GetFullName(), "answered": "false"}).Set(float64(unanswered))
+
+	return nil
+}
+
+const topIssuesGraphQLQuery = `
+query($query: String!, $first: Int!) {
+	search(query: $query, type: ISSUE, first: $first) {
+		nodes {
+			... on Issue {
+				number
+				reactions(content: THUMBS_UP) { totalCount }
+				comments { totalCount }
+			}
+		}
+	}
+}`
+
+type graphQLTopIssuesResponse struct {
+	Data struct {
+		Search struct {
+			Nodes []struct {
+				Number    int               `json:"number"`
+				Reactions graphQLTotalCount `json:"reactions"`
+				Comments  graphQLTotalCount `json:"comments"`
+			} `json:"nodes"`
+		} `json:"search"`
+	} `json:"data"`
+}
+
+func updateTopIssueMetrics(ctx context.Context, client *githubClient, opts *collectorOptions, repo *github.Repository) error {
+	variables := map[string]any{
+		"query": "repo:" + repo.GetFullName() + " is:issue is:open sort:reactions-+1-desc",
+		"first": opts.TopIssues,
+	}
+
+	var response graphQLTopIssuesResponse
+	if err := executeGraphQL(client, ctx, topIssuesGraphQLQuery, variables, &response); err != nil {
+		return err
+	}
+
+	// The set of top issues changes over time, so drop the previous set.
+	repoLabels := prometheus.Labels{"github_repo": repo.GetFullName()}
+	issueThumbsUpCount.DeletePartialMatch(repoLabels)
+	issueCommentCount.DeletePartialMatch(repoLabels)
+
+	for _, issue := range response.Data.Search.Nodes {
+		labels := prometheus.Labels{
+			"github_repo": repo.GetFullName(),
+			"number":      fmt.Sprintf("%d", issue.Number),
+		}
+		issueThumbsUpCount.With(labels).Set(float64(issue.Reactions.TotalCount))
+		issueCommentCount.With(labels).Set(float64(issue.Comments.TotalCount))
+	}
+
+	return nil
+}
+
+var alertSeverities = []string{"critical", "high", "medium", "low"}
+
+func updateDependabotAlertMetrics(ctx context.Context, client *githubClient, opts *collectorOptions, repo *github.Repository) error {
+	counts, err := countDependabotAlerts(func(listOpts *github.ListAlertsOptions) ([]*github.DependabotAlert, *github.Response, error) {
+		return client.Dependabot.ListRepoAlerts(ctx, repo.GetOwner().GetLogin(), repo.GetName(), listOpts)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, severity := range alertSeverities {
+		dependabotAlertCount.With(prometheus.Labels{
+			"github_repo": repo.GetFullName(),
+			"severity":    severity,
+		}).Set(float64(counts[severity]))
+	}
+
+	return nil
+}
+
+// countDependabotAlerts pages through open Dependabot alerts, counting them
+// by advisory severity.
+func countDependabotAlerts(list func(*github.ListAlertsOptions) ([]*github.DependabotAlert, *github.Response, error)) (map[string]int, error) {
+	listOpts := &github.ListAlertsOptions{State: github.Ptr("open")}
+	listOpts.ListCursorOptions.PerPage = 100
+
+	counts := make(map[string]int)
+	for {
+		alerts, resp, err := list(listOpts)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, alert := range alerts {
+			counts[alert.GetSecurityAdvisory().GetSeverity()]++
+		}
+
+		if resp.After == "" {
+			return counts, nil
+		}
+		listOpts.After = resp.After
+	}
+}
+
+type secretScanningAlertKey struct {
+	secretType string
+	state      string
+}
+
+func updateSecretScanningAlertMetrics(ctx context.Context, client *githubClient, opts *collectorOptions, repo *github.Repository) error {
+	counts, err := countSecretScanningAlerts(func(listOpts *github.SecretScanningAlertListOptions) ([]*github.SecretScanningAlert, *github.Response, error) {
+		return client.SecretScanning.ListAlertsForRepo(ctx, repo.GetOwner().GetLogin(), repo.GetName(), listOpts)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Secret types come and go with alerts, so drop the previous set.
+	secretScanningAlertCount.DeletePartialMatch(prometheus.Labels{"github_repo": repo.GetFullName()})
+	for key, count := range counts {
+		secretScanningAlertCount.With(prometheus.Labels{
+			"github_repo": repo.GetFullName(),
+			"secret_type": key.secretType,
+			"state":       key.state,
+		}).Set(float64(count))
+	}
+
+	return nil
+}
+
+// countSecretScanningAlerts pages through secret scanning alerts, counting
+// them by secret type and state.
+func countSecretScanningAlerts(list func(*github.SecretScanningAlertListOptions) ([]*github.SecretScanningAlert, *github.Response, error)) (map[secretScanningAlertKey]int, error) {
+	listOpts := &github.SecretScanningAlertListOptions{}
+	listOpts.ListOptions.PerPage = 100
+
+	counts := make(map[secretScanningAlertKey]int)
+	for {
+		alerts, resp, err := list(listOpts)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, alert := range alerts {
+			counts[secretScanningAlertKey{secretType: alert.GetSecretType(), state: alert.GetState()}]++
+		}
+
+		if resp.NextPage == 0 {
+			return counts, nil
+		}
+		listOpts.ListOptions.Page = resp.NextPage
+	}
+}
+
+func updateOrgSecurityAlertMetrics(ctx context.Context, client *githubClient, opts *collectorOptions) error {
+	for _, org := range opts.Orgs {
+		dependabotCounts, err := countDependabotAlerts(func(listOpts *github.ListAlertsOptions) ([]*github.DependabotAlert, *github.Response, error) {
+			return client.Dependabot.ListOrgAlerts(ctx, org, listOpts)
+		})
+		if err != nil {
+			return fmt.Errorf("%s dependabot alerts: %w", org, err)
+		}
+		for _, severity := range alertSeverities {
+			orgDependabotAlertCount.With(prometheus.Labels{
+				"org":      org,
+				"severity": severity,
+			}).Set(float64(dependabotCounts[severity]))
+		}
+
+		codeScanningCounts, err := countOrgCodeScanningAlerts(ctx, client, org)
+		if err != nil {
+			return fmt.Errorf("%s code scanning alerts: %w", org, err)
+		}
+		orgCodeScanningAlertCount.DeletePartialMatch(prometheus.Labels{"org": org})
+		for severity, count := range codeScanningCounts {
+			orgCodeScanningAlertCount.With(prometheus.Labels{
+				"org":      org,
+				"severity": severity,
+			}).Set(float64(count))
+		}
+
+		secretCounts, err := countSecretScanningAlerts(func(listOpts *github.SecretScanningAlertListOptions) ([]*github.SecretScanningAlert, *github.Response, error) {
+			return client.SecretScanning.ListAlertsForOrg(ctx, org, listOpts)
+		})
+		if err != nil {
+			return fmt.Errorf("%s secret scanning alerts: %w", org, err)
+		}
+		stateCounts := map[string]int{"open": 0, "resolved": 0}
+		for key, count := range secretCounts {
+			stateCounts[key.state] += count
+		}
+		for state, count := range stateCounts {
+			orgSecretScanningAlertCount.With(prometheus.Labels{
+				"org":   org,
+				"state": state,
+			}).Set(float64(count))
+		}
+	}
+
+	return nil
+}
+
+// countOrgCodeScanningAlerts counts open code scanning alerts by security
+// severity, falling back to the rule severity for non-security rules.
+func countOrgCodeScanningAlerts(ctx context.Context, client *githubClient, org string) (map[string]int, error) {
+	listOpts := &github.AlertListOptions{State: "open"}
+	listOpts.ListOptions.PerPage = 100
+
+	counts := make(map[string]int)
+	for {
+		alerts, resp, err := client.CodeScanning.ListAlertsForOrg(ctx, org, listOpts)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, alert := range alerts {
+			severity := alert.GetRule().GetSecuritySeverityLevel()
+			if severity == "" {
+				severity = alert.GetRule().GetSeverity()
+			}
+			counts[severity]++
+		}
+
+		if resp.NextPage == 0 {
+			return counts, nil
+		}
+		listOpts.ListOptions.Page = resp.NextPage
+	}
+}
+
+// spdxSBOM is the subset of the dependency graph SBOM export needed to
+// classify packages. The go-github SBOM type omits relationships and
+// external references.
+type spdxSBOM struct {
+	SBOM struct {
+		DocumentDescribes []string `json:"documentDescribes"`
+		Packages          []struct {
+			SPDXID       string `json:"SPDXID"`
+			ExternalRefs []struct {
+				ReferenceType    string `json:"referenceType"`
+				ReferenceLocator string `json:"referenceLocator"`
+			} `json:"externalRefs"`
+		} `json:"packages"`
+		Relationships []struct {
+			SPDXElementID      string `json:"spdxElementId"`
+			RelationshipType   string `json:"relationshipType"`
+			RelatedSPDXElement string `json:"relatedSpdxElement"`
+		} `json:"relationships"`
+	} `json:"sbom"`
+}
+
+type dependencyKey struct {
+	ecosystem    string
+	relationship string
+}
+
+func updateDependencyMetrics(ctx context.Context, client *githubClient, opts *collectorOptions, repo *github.Repository) error {
+	req, err := client.NewRequest("GET", fmt.Sprintf("repos/%s/dependency-graph/sbom", repo.GetFullName()), nil)
+	if err != nil {
+		return err
+	}
+
+	var sbom spdxSBOM
+	if _, err := client.Do(ctx, req, &sbom); err != nil {
+		return err
+	}
+
+	roots := make(map[string]bool)
+	for _, id := range sbom.SBOM.DocumentDescribes {
+		roots[id] = true
+	}
+
+	direct := make(map[string]bool)
+	for _, rel := range sbom.SBOM.Relationships {
+		if rel.RelationshipType == "DEPENDS_ON" && roots[rel.SPDXElementID] {
+			direct[rel.RelatedSPDXElement] = true
+		}
+	}
+
+	counts := make(map[dependencyKey]int)
+	for _, pkg := range sbom.SBOM.Packages {
+		if roots[pkg.SPDXID] {
+			continue
+		}
+
+		ecosystem := "unknown"
+		for _, ref := range pkg.ExternalRefs {
+			if ref.ReferenceType == "purl" {
+				ecosystem = purlType(ref.ReferenceLocator)
+				break
+			}
+		}
+
+		relationship := "transitive"
+		if direct[pkg.SPDXID] {
+			relationship = "direct"
+		}
+		counts[dependencyKey{ecosystem: ecosystem, relationship: relationship}]++
+	}
+
+	dependencyCount.DeletePartialMatch(prometheus.Labels{"github_repo": repo.GetFullName()})
+	for key, count := range counts {
+		dependencyCount.With(prometheus.Labels{
+			"github_repo":  repo.GetFullName(),
+			"ecosystem":    key.ecosystem,
+			"relationship": key.relationship,
+		}).Set(float64(count))
+	}
+
+	return nil
+}
+
+// purlType returns the package type of a package URL such as
+// pkg:npm/left-pad@1.3.0.
+func purlType(purl string) string {
+	rest, ok := strings.CutPrefix(purl, "pkg:")
+	if !ok {
+		return "unknown"
+	}
+	purlType, _, _ := strings.Cut(rest, "/")
+	return purlType
+}
+
+var dependentsPattern = regexp.MustCompile(`([\d,]+)\s+(Repositor(?:y|ies)|Packages?)\b`)
+
+// updateDependentsMetrics scrapes the dependency network page, since the
+// dependents counts are not available from the REST or GraphQL APIs. The page
+// is not an API and a change to its markup fails the collector.
+func updateDependentsMetrics(ctx context.Context, client *githubClient, opts *collectorOptions, repo *github.Repository) error {
+	if repo.GetPrivate() {
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", repo.GetHTMLURL()+"/network/dependents", nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Web.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status fetching dependents: %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	matches := dependentsPattern.FindAllSubmatch(body, -1)
+	if len(matches) == 0 {
+		return fmt.Errorf("no dependents counts found on %s/network/dependents, the page may have changed", repo.GetHTMLURL())
+	}
+
+	counts := map[string]int{"repository": 0, "package": 0}
+	for _, match := range matches {
+		count, err := strconv.Atoi(strings.ReplaceAll(string(match[1]), ",", ""))
+		if err != nil {
+			continue
+		}
+		if bytes.HasPrefix(match[2], []byte("Repositor")) {
+			counts["repository"] = count
+		} else {
+			counts["package"] = count
+		}
+	}
+
+	for dependentType, count := range counts {
+		dependentsCount.With(prometheus.Labels{
+			"github_repo": repo.GetFullName(),
+			"type":        dependentType,
+		}).Set(float64(count))
+	}
+
+	return nil
+}
+
+func updateSecurityFeatureMetrics(ctx context.Context, client *githubClient, opts *collectorOptions, repo *github.Repository) error {
+	owner, repoName := repo.GetOwner().GetLogin(), repo.GetName()
+
+	dependabotAlerts, _, err := client.Repositories.GetVulnerabilityAlerts(ctx, owner, repoName)
+	if err != nil {
+		return err
+	}
+
+	// The repository listing omits security_and_analysis, so fetch the full repository.
+	fullRepo, _, err := client.Repositories.Get(ctx, owner, repoName)
+	if err != nil {
+		return err
+	}
+	analysis := fullRepo.GetSecurityAndAnalysis()
+
+	features := map[string]bool{
+		"dependabot_alerts":           dependabotAlerts,
+		"dependabot_security_updates": analysis.GetDependabotSecurityUpdates().GetStatus() == "enabled",
+		"secret_scanning":             analysis.GetSecretScanning().GetStatus() == "enabled",
+		"push_protection":             analysis.GetSecretScanningPushProtection().GetStatus() == "enabled",
+	}
+
+	for feature, enabled := range features {
+		securityFeatureEnabled.With(prometheus.Labels{
+			"github_repo": repo.GetFullName(),
+			"feature":     feature,
+		}).Set(boolToFloat(enabled))
+	}
+
+	return nil
+}
+
+func updateCommunityMetrics(ctx context.Context, client *githubClient, opts *collectorOptions, repo *github.Repository) error {
+	health, _, err := client.Repositories.GetCommunityHealthMetrics(ctx, repo.GetOwner().GetLogin(), repo.GetName())
+	if err != nil {
+		return err
+	}
+
+	communityHealthPercentage.With(prometheus.Labels{"github_repo": repo.GetFullName()}).Set(float64(health.GetHealthPercentage()))
+
+	// The community profile does not report security policies.
+	variables := map[string]any{
+		"owner": repo.GetOwner().GetLogin(),
+		"name":  repo.GetName(),
+	}
+	var response graphQLSecurityPolicyResponse
+	if err := executeGraphQL(client, ctx, securityPolicyGraphQLQuery, variables, &response); err != nil {
+		return err
+	}
+
+	files := health.GetFiles()
+	present := map[string]bool{
+		"readme":          files.GetReadme() != nil,
+		"license":         files.GetLicense() != nil,
+		"contributing":    files.GetContributing() != nil,
+		"code_of_conduct": files.GetCodeOfConduct() != nil || files.GetCodeOfConductFile() != nil,
+		"security":        response.Data.Repository.IsSecurityPolicyEnabled,
+	}
+	for file, ok := range present {
+		communityFilePresent.With(prometheus.Labels{
+			"github_repo": repo.GetFullName(),
+			"file":        file,
+		}).Set(boolToFloat(ok))
+	}
+
+	return nil
+}
+
+const securityPolicyGraphQLQuery = `
+query($owner: String!, $name: String!) {
+	repository(owner: $owner, name: $name) {
+		isSecurityPolicyEnabled
+	}
+}`
+
+type graphQLSecurityPolicyResponse struct {
+	Data struct {
+		Repository struct {
+			IsSecurityPolicyEnabled bool `json:"isSecurityPolicyEnabled"`
+		} `json:"repository"`
+	} `json:"data"`
+}
+
+const teamsGraphQLQuery = `
+query($org: String!, $cursor: String, $perPage: Int!) {
+	organization(login: $org) {
+		teams(first: $perPage, after: $cursor) {
+			nodes {
+				slug
+				members { totalCount }
+				repositories { totalCount }
+			}
+			pageInfo { hasNextPage endCursor }
+		}
+	}
+}`
+
+type graphQLTeamsResponse struct {
+	Data struct {
+		Organization struct {
+			Teams struct {
+				Nodes []struct {
+					Slug         string            `json:"slug"`
+					Members      graphQLTotalCount `json:"members"`
+					Repositories graphQLTotalCount `json:"repositories"`
+				} `json:"nodes"`
+				PageInfo graphQLPageInfo `json:"pageInfo"`
+			} `json:"teams"`
+		} `json:"organization"`
+	} `json:"data"`
+}
+
+func updateTeamMetrics(ctx context.Context, client *githubClient, opts *collectorOptions) error {
+	for _, org := range opts.Orgs {
+		variables := map[string]any{"org": org, "perPage": opts.PerPage}
+
+		// Deleted teams are only dropped once every page has been read, so a
+		// failed page leaves the previous values in place.
+		memberCounts := make(map[string]int)
+		repoCounts := make(map[string]int)
+		for {
+			var response graphQLTeamsResponse
+			if err := executeGraphQL(client, ctx, teamsGraphQLQuery, variables, &response); err != nil {
+				return fmt.Errorf("%s teams: %w", org, err)
+			}
+
+			teams := response.Data.Organization.Teams
+			for _, team := range teams.Nodes {
+				memberCounts[team.Slug] = team.Members.TotalCount
+				repoCounts[team.Slug] = team.Repositories.TotalCount
+			}
+
+			if !teams.PageInfo.HasNextPage {
+				break
+			}
+			variables["cursor"] = teams.PageInfo.EndCursor
+		}
+
+		teamMemberCount.DeletePartialMatch(prometheus.Labels{"org": org})
+		teamRepoCount.DeletePartialMatch(prometheus.Labels{"org": org})
+		for team, count := range memberCounts {
+			labels := prometheus.Labels{
+				"org":  org,
+				"team": team,
+			}
+			teamMemberCount.With(labels).Set(float64(count))
+			teamRepoCount.With(labels).Set(float64(repoCounts[team]))
+		}
+	}
+
+	return nil
+}
+
+func updateOrgInvitationMetrics(ctx context.Context, client *githubClient, opts *collectorOptions) error {
+	for _, org := range opts.Orgs {
+		listOpts := &github.ListOptions{PerPage: opts.PerPage}
+
+		count := 0
+		var oldest time.Time
+		for {
+			invitations, resp, err := client.Organizations.ListPendingOrgInvitations(ctx, org, listOpts)
+			if err != nil {
+				return fmt.Errorf("%s invitations: %w", org, err)
+			}
+
+			for _, invitation := range invitations {
+				count++
+				if created := invitation.GetCreatedAt().Time; oldest.IsZero() || created.Before(oldest) {
+					oldest = created
+				}
+			}
+
+			if resp.NextPage == 0 {
+				break
+			}
+			listOpts.Page = resp.NextPage
+		}
+
+		labels := prometheus.Labels{"org": org}
+		orgPendingInvitationCount.With(labels).Set(float64(count))
+		if oldest.IsZero() {
+			orgPendingInvitationOldestAge.Delete(labels)
+		} else {
+			orgPendingInvitationOldestAge.With(labels).Set(time.Since(oldest).Seconds())
+		}
+	}
+
+	return nil
+}
+
+func updateOrg2FAMetrics(ctx context.Context, client *githubClient, opts *collectorOptions) error {
+	for _, org := range opts.Orgs {
+		count, err := countAll(func(page int) ([]*github.User, *github.Response, error) {
+			return client.Organizations.ListMembers(ctx, org, &github.ListMembersOptions{
+				Filter:      "2fa_disabled",
+				ListOptions: github.ListOptions{PerPage: opts.PerPage, Page: page},
+			})
+		})
+		if err != nil {
+			return fmt.Errorf("%s members: %w", org, err)
+		}
+
+		orgMembersWithout2FA.With(prometheus.Labels{"org": org}).Set(float64(count))
+	}
+
+	return nil
+}
+
+// countAll pages through a list endpoint and returns the total number of items.
+func countAll[T any](list func(page int) ([]T, *github.Response, error)) (int, error) {
+	count, page := 0, 0
+	for {
+		items, resp, err := list(page)
+		if err != nil {
+			return 0, err
+		}
+
+		count += len(items)
+
+		if resp.NextPage == 0 {
+			return count, nil
+		}
+		page = resp.NextPage
+	}
+}
+
+func updateOrgOutsideCollaboratorMetrics(ctx context.Context, client *githubClient, opts *collectorOptions) error {
+	for _, org := range opts.Orgs {
+		count, err := countAll(func(page int) ([]*github.User, *github.Response, error) {
+			return client.Organizations.ListOutsideCollaborators(ctx, org, &github.ListOutsideCollaboratorsOptions{
+				ListOptions: github.ListOptions{PerPage: opts.PerPage, Page: page},
+			})
+		})
+		if err != nil {
+			return fmt.Errorf("%s outside collaborators: %w", org, err)
+		}
+
+		orgOutsideCollaboratorCount.With(prometheus.Labels{"org": org}).Set(float64(count))
+	}
+
+	return nil
+}
+
+func updateOutsideCollaboratorMetrics(ctx context.Context, client *githubClient, opts *collectorOptions, repo *github.Repository) error {
+	count, err := countCollaborators(ctx, client, opts, repo, "outside")
+	if err != nil {
+		return err
+	}
+
+	repoOutsideCollaboratorCount.With(prometheus.Labels{"github_repo": repo.GetFullName()}).Set(float64(count))
+
+	return nil
+}
+
+// updateCollaboratorMetrics exports the direct collaborator count, to review
+// access sprawl per repository. Outside collaborators, who are also direct
+// collaborators, have their own collector.
+func updateCollaboratorMetrics(ctx context.Context, client *githubClient, opts *collectorOptions, repo *github.Repository) error {
+	count, err := countCollaborators(ctx, client, opts, repo, "direct")
+	if err != nil {
+		return err
+	}
+
+	repoDirectCollaboratorCount.With(prometheus.Labels{"github_repo": repo.GetFullName()}).Set(float64(count))
+
+	return nil
+}
+
+func countCollaborators(ctx context.Context, client *githubClient, opts *collectorOptions, repo *github.Repository, affiliation string) (int, error) {
+	return countAll(func(page int) ([]*github.User, *github.Response, error) {
+		return client.Repositories.ListCollaborators(ctx, repo.GetOwner().GetLogin(), repo.GetName(), &github.ListCollaboratorsOptions{
+			Affiliation: affiliation,
+			ListOptions: github.ListOptions{PerPage: opts.PerPage, Page: page},
+		})
+	})
+}
+
+func updateOrgAppMetrics(ctx context.Context, client *githubClient, opts *collectorOptions) error {
+	for _, org := range opts.Orgs {
+		listOpts := &github.ListOptions{PerPage: opts.PerPage}
+
+		var slugs []string
+		for {
+			installations, resp, err := client.Organizations.ListInstallations(ctx, org, listOpts)
+			if err != nil {
+				return fmt.Errorf("%s installations: %w", org, err)
+			}
+
+			for _, installation := range installations.Installations {
+				slugs = append(slugs, installation.GetAppSlug())
+			}
+
+			if resp.NextPage == 0 {
+				break
+			}
+			listOpts.Page = resp.NextPage
+		}
+
+		orgAppInstallationCount.With(prometheus.Labels{"org": org}).Set(float64(len(slugs)))
+
+		// Drop apps uninstalled since the last update.
+		orgAppInstallationInfo.DeletePartialMatch(prometheus.Labels{"org": org})
+		for _, slug := range slugs {
+			orgAppInstallationInfo.With(prometheus.Labels{
+				"org":      org,
+				"app_slug": slug,
+			}).Set(1)
+		}
+	}
+
+	return nil
+}
+
+func updateOrgPlanMetrics(ctx context.Context, client *githubClient, opts *collectorOptions) error {
+	for _, org := range opts.Orgs {
+		organization, _, err := client.Organizations.Get(ctx, org)
+		if err != nil {
+			return fmt.Errorf("%s: %w", org, err)
+		}
+
+		// The plan is only visible to organization owners.
+		plan := organization.GetPlan()
+		if plan == nil {
+			continue
+		}
+
+		labels := prometheus.Labels{"org": org}
+		orgPlanInfo.DeletePartialMatch(labels)
+		orgPlanInfo.With(prometheus.Labels{
+			"org":  org,
+			"plan": plan.GetName(),
+		}).Set(1)
+		orgSeats.With(labels).Set(float64(plan.GetSeats()))
+		orgFilledSeats.With(labels).Set(float64(plan.GetFilledSeats()))
+	}
+
+	return nil
+}
+
+// consumedLicenses is the summary of the enterprise consumed licenses
+// response, which go-github does not model.
+type consumedLicenses struct {
+	TotalSeatsConsumed  int `json:"total_seats_consumed"`
+	TotalSeatsPurchased int `json:"total_seats_purchased"`
+}
+
+func updateEnterpriseLicenseMetrics(ctx context.Context, client *githubClient, opts *collectorOptions) error {
+	for _, enterprise := range opts.Enterprises {
+		// Totals are included on every page, so skip the per-user listing.
+		req, err := client.NewRequest("GET", fmt.Sprintf("enterprises/%s/consumed-licenses?per_page=1", enterprise), nil)
+		if err != nil {
+			return err
+		}
+
+		var licenses consumedLicenses
+		if _, err := client.Do(ctx, req, &licenses); err != nil {
+			return fmt.Errorf("%s consumed licenses: %w", enterprise, err)
+		}
+
+		labels := prometheus.Labels{"enterprise": enterprise}
+		enterpriseSeatsPurchased.With(labels).Set(float64(licenses.TotalSeatsPurchased))
+		enterpriseSeatsConsumed.With(labels).Set(float64(licenses.TotalSeatsConsumed))
+	}
+
+	return nil
+}
+
+const copilotInactiveAge = 30 * 24 * time.Hour
+
+func updateCopilotMetrics(ctx context.Context, client *githubClient, opts *collectorOptions) error {
+	cutoff := time.Now().Add(-copilotInactiveAge)
+
+	for _, org := range opts.Orgs {
+		billing, _, err := client.Copilot.GetCopilotBilling(ctx, org)
+		if err != nil {
+			return fmt.Errorf("%s copilot billing: %w", org, err)
+		}
+
+		listOpts := &github.ListOptions{PerPage: opts.PerPage}
+		inactive := 0
+		for {
+			seats, resp, err := client.Copilot.ListCopilotSeats(ctx, org, listOpts)
+			if err != nil {
+				return fmt.Errorf("%s copilot seats: %w", org, err)
+			}
+
+			for _, seat := range seats.Seats {
+				// Seats that were never used count from their assignment.
+				lastActive := seat.GetCreatedAt().Time
+				if seat.LastActivityAt != nil {
+					lastActive = seat.GetLastActivityAt().Time
+				}
+				if lastActive.Before(cutoff) {
+					inactive++
+				}
+			}
+
+			if resp.NextPage == 0 {
+				break
+			}
+			listOpts.Page = resp.NextPage
+		}
+
+		breakdown := billing.SeatBreakdown
+		if breakdown == nil {
+			breakdown = &github.CopilotSeatBreakdown{}
+		}
+		for state, count := range map[string]int{
+			"total":    breakdown.Total,
+			"active":   breakdown.ActiveThisCycle,
+			"inactive": inactive,
+		} {
+			copilotSeatCount.With(prometheus.Labels{
+				"org":   org,
+				"state": state,
+			}).Set(float64(count))
+		}
+	}
+
+	return nil
+}
+
+func updateBillingMetrics(ctx context.Context, client *githubClient, opts *collectorOptions) error {
+	user, _, err := client.Users.Get(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	if err := updateOwnerBillingMetrics(ctx, client, user.GetLogin(), false); err != nil {
+		return fmt.Errorf("%s: %w", user.GetLogin(), err)
+	}
+	for _, org := range opts.Orgs {
+		if err := updateOwnerBillingMetrics(ctx, client, org, true); err != nil {
+			return fmt.Errorf("%s: %w", org, err)
+		}
+	}
+
+	return nil
+}
+
+func updateOwnerBillingMetrics(ctx context.Context, client *githubClient, owner string, isOrg bool) error {
+	getActions, getPackages, getStorage := client.Billing.GetActionsBillingUser, client.Billing.GetPackagesBillingUser, client.Billing.GetStorageBillingUser
+	if isOrg {
+		getActions, getPackages, getStorage = client.Billing.GetActionsBillingOrg, client.Billing.GetPackagesBillingOrg, client.Billing.GetStorageBillingOrg
+	}
+
+	actions, _, err := getActions(ctx, owner)
+	if err != nil {
+		return fmt.Errorf("actions billing: %w", err)
+	}
+	packages, _, err := getPackages(ctx, owner)
+	if err != nil {
+		return fmt.Errorf("packages billing: %w", err)
+	}
+	storage, _, err := getStorage(ctx, owner)
+	if err != nil {
+		return fmt.Errorf("storage billing: %w", err)
+	}
+
+	labels := prometheus.Labels{"owner": owner}
+	billingActionsMinutesUsed.With(labels).Set(actions.TotalMinutesUsed)
+	billingActionsMinutesIncluded.With(labels).Set(actions.IncludedMinutes)
+	billingActionsPaidMinutesUsed.With(labels).Set(actions.TotalPaidMinutesUsed)
+	billingPackagesBandwidthUsed.With(labels).Set(float64(packages.TotalGigabytesBandwidthUsed))
+	billingPackagesBandwidthIncluded.With(labels).Set(packages.IncludedGigabytesBandwidth)
+	billingSharedStorageEstimated.With(labels).Set(storage.EstimatedStorageForMonth)
+	billingSharedStorageEstimatedPaid.With(labels).Set(storage.EstimatedPaidStorageForMonth)
+	billingDaysLeftInCycle.With(labels).Set(float64(storage.DaysLeftInBillingCycle))
+
+	return nil
+}
+
+func updateGistMetrics(ctx context.Context, client *githubClient, opts *collectorOptions) error {
+	listOpts := &github.GistListOptions{ListOptions: github.ListOptions{PerPage: opts.PerPage}}
+
+	counts := map[string]int{"public": 0, "secret": 0}
+	lastUpdated := make(map[string]time.Time)
+	for {
+		gists, resp, err := client.Gists.List(ctx, "", listOpts)
+		if err != nil {
+			return err
+		}
+
+		for _, gist := range gists {
+			visibility := "secret"
+			if gist.GetPublic() {
+				visibility = "public"
+			}
+			counts[visibility]++
+			if updated := gist.GetUpdatedAt().Time; updated.After(lastUpdated[visibility]) {
+				lastUpdated[visibility] = updated
+			}
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		listOpts.Page = resp.NextPage
+	}
+
+	for visibility, count := range counts {
+		labels := prometheus.Labels{"visibility": visibility}
+		gistCount.With(labels).Set(float64(count))
+		if updated, ok := lastUpdated[visibility]; ok {
+			gistLastUpdatedTimestamp.With(labels).Set(float64(updated.Unix()))
+		}
+	}
+
+	return nil
+}
+
+const sponsorsGraphQLQuery = `
+query($org: String!, $isOrg: Boolean!) {
+	viewer @skip(if: $isOrg) { ...sponsorable }
+	organization(login: $org) @include(if: $isOrg) { ...sponsorable }
+}
+
+fragment sponsorable on Sponsorable {
+	... on Actor { login }
+	sponsors { totalCount }
+	monthlyEstimatedSponsorsIncomeInCents
+}`
+
+type graphQLSponsorable struct {
+	Login                                 string            `json:"login"`
+	Sponsors                              graphQLTotalCount `json:"sponsors"`
+	MonthlyEstimatedSponsorsIncomeInCents int               `json:"monthlyEstimatedSponsorsIncomeInCents"`
+}
+
+type graphQLSponsorsResponse struct {
+	Data struct {
+		Viewer       *graphQLSponsorable `json:"viewer"`
+		Organization *graphQLSponsorable `json:"organization"`
+	} `json:"data"`
+}
+
+func updateSponsorMetrics(ctx context.Context, client *githubClient, opts *collectorOptions) error {
+	// An empty org queries the authenticated user.
+	for _, org := range append([]string{""}, opts.Orgs...) {
+		variables := map[string]any{
+			"org":   org,
+			"isOrg": org != "",
+		}
+
+		var response graphQLSponsorsResponse
+		if err := executeGraphQL(client, ctx, sponsorsGraphQLQuery, variables, &response); err != nil {
+			return err
+		}
+
+		sponsorable := response.Data.Viewer
+		if org != "" {
+			sponsorable = response.Data.Organization
+		}
+		if sponsorable == nil {
+			continue
+		}
+
+		labels := prometheus.Labels{"owner": sponsorable.Login}
+		sponsorCount.With(labels).Set(float64(sponsorable.Sponsors.TotalCount))
+		sponsorMonthlyIncome.With(labels).Set(float64(sponsorable.MonthlyEstimatedSponsorsIncomeInCents) / 100)
+	}
+
+	return nil
+}
+
+func updateFollowerMetrics(ctx context.Context, client *githubClient, opts *collectorOptions) error {
+	// An empty login fetches the authenticated user.
+	for _, login := range append([]string{""}, opts.Users...) {
+		user, _, err := client.Users.Get(ctx, login)
+		if err != nil {
+			return fmt.Errorf("user %q: %w", login, err)
+		}
+
+		labels := prometheus.Labels{"user": user.GetLogin()}
+		userFollowers.With(labels).Set(float64(user.GetFollowers()))
+		userFollowing.With(labels).Set(float64(user.GetFollowing()))
+	}
+
+	return nil
+}
+
+const starredGraphQLQuery = `
+query {
+	viewer {
+		starredRepositories { totalCount }
+	}
+}`
+
+type graphQLStarredResponse struct {
+	Data struct {
+		Viewer struct {
+			StarredRepositories graphQLTotalCount `json:"starredRepositories"`
+		} `json:"viewer"`
+	} `json:"data"`
+}
+
+func updateStarredMetrics(ctx context.Context, client *githubClient, opts *collectorOptions) error {
+	var response graphQLStarredResponse
+	if err := executeGraphQL(client, ctx, starredGraphQLQuery, nil, &response); err != nil {
+		return err
+	}
+	starredRepoCount.Set(float64(response.Data.Viewer.StarredRepositories.TotalCount))
+
+	for _, fullName := range opts.UpstreamRepos {
+		owner, name, ok := strings.Cut(fullName, "/")
+		if !ok {
+			return fmt.Errorf("invalid upstream repository %q", fullName)
+		}
+
+		// Upstream releases share the latest release metrics of owned repositories.
+		repo := &github.Repository{
+			Owner:    &github.User{Login: github.Ptr(owner)},
+			Name:     github.Ptr(name),
+			FullName: github.Ptr(fullName),
+		}
+		if err := updateLatestReleaseMetrics(ctx, client, opts, repo); err != nil {
+			return fmt.Errorf("%s latest release: %w", fullName, err)
+		}
+	}
+
+	return nil
+}
+
+const watchingGraphQLQuery = `
+query {
+	viewer {
+		watching { totalCount }
+	}
+}`
+
+type graphQLWatchingResponse struct {
+	Data struct {
+		Viewer struct {
+			Watching graphQLTotalCount `json:"watching"`
+		} `json:"viewer"`
+	} `json:"data"`
+}
+
+func updateWatchingMetrics(ctx context.Context, client *githubClient, opts *collectorOptions) error {
+	var response graphQLWatchingResponse
+	if err := executeGraphQL(client, ctx, watchingGraphQLQuery, nil, &response); err != nil {
+		return err
+	}
+	watchedRepoCount.Set(float64(response.Data.Viewer.Watching.TotalCount))
+	return nil
+}
+
+var statusPageStatuses = []string{"operational", "degraded_performance", "partial_outage", "major_outage", "under_maintenance"}
+
+type statusPageSummary struct {
+	Components []struct {
+		Name   string `json:"name"`
+		Status string `json:"status"`
+		Group  bool   `json:"group"`
+	} `json:"components"`
+}
+
+// updateStatusPageMetrics polls the public githubstatus.com summary, which
+// does not need the GitHub API client.
+func updateStatusPageMetrics(ctx context.Context, client *githubClient, opts *collectorOptions) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", "https://www.githubstatus.com/api/v2/summary.json", nil)
+	if err != nil {
+		return err
+	}
+
+	// The API client's transport would send the token to a third party, so
+	// only its --request-timeout is carried over.
+	httpClient := &http.Client{Timeout: client.Client().Timeout}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status fetching status page: %s", resp.Status)
+	}
+
+	var summary statusPageSummary
+	if err := json.NewDecoder(resp.Body).Decode(&summary); err != nil {
+		return err
+	}
+
+	for _, component := range summary.Components {
+		if component.Group {
+			continue
+		}
+		for _, status := range statusPageStatuses {
+			statusComponentStatus.With(prometheus.Labels{
+				"component": component.Name,
+				"status":    status,
+			}).Set(boolToFloat(status == component.Status))
+		}
+	}
+
+	return nil
+}
+
+var eventTypes = map[string]string{
+	"PushEvent":        "push",
+	"PullRequestEvent": "pull_request",
+	"IssuesEvent":      "issues",
+	"ReleaseEvent":     "release",
+}
+
+var eventWindows = map[string]time.Duration{
+	"1h":  time.Hour,
+	"24h": 24 * time.Hour,
+}
+
+func updateEventMetrics(ctx context.Context, client *githubClient, opts *collectorOptions, repo *github.Repository) error {
+	owner := repo.GetOwner().GetLogin()
+	repoName := repo.GetName()
+
+	now := time.Now()
+	since := now.Add(-24 * time.Hour)
+
+	var events []*github.Event
+	listOpts := &github.ListOptions{PerPage: opts.PerPage}
+	for {
+		page, resp, err := client.Activity.ListRepositoryEvents(ctx, owner, repoName, listOpts)
+		if err != nil {
+			return err
+		}
+		events = append(events, page...)
+
+		// Events are returned newest first.
+		if len(page) == 0 || page[len(page)-1].GetCreatedAt().Before(since) || resp.NextPage == 0 {
+			break
+		}
+		listOpts.Page = resp.NextPage
+	}
+
+	for window, d := range eventWindows {
+		counts := make(map[string]int)
+		for _, event := range events {
+			if eventType, ok := eventTypes[event.GetType()]; ok && now.Sub(event.GetCreatedAt().Time) <= d {
+				counts[eventType]++
+			}
+		}
+		for _, eventType := range eventTypes {
+			repoEventCount.With(prometheus.Labels{
+				"github_repo": repo.GetFullName(),
+				"type":        eventType,
+				"window":      window,
+			}).Set(float64(counts[eventType]))
+		}
+	}
+
+	return nil
+}
+
+// runCheck prints the token's identity, rate limits and scopes, and runs each
+// enabled collector once, reporting whether everything works.
+func runCheck(ctx context.Context, client *githubClient, opts *collectorOptions) bool {
+	ok := true
+
+	user, resp, err := client.Users.Get(ctx, "")
+	if err != nil {
+		fmt.Printf("token: FAIL: %v\n", err)
+		return false
+	}
+	fmt.Printf("token: ok, authenticated as %s\n", user.GetLogin())
+	if !resp.TokenExpiration.IsZero() {
+		fmt.Printf("token expires: %s\n", resp.TokenExpiration.Format(time.RFC3339))
+	}
+
+	if granted, reported := tokenScopes(resp); reported {
+		fmt.Printf("scopes: %s\n", strings.Join(granted, ", "))
+		for _, missing := range missingScopes(granted, opts) {
+			fmt.Printf("scopes: FAIL: missing %s\n", missing)
+			ok = false
+		}
+	} else {
+		fmt.Println("scopes: not reported for this token type")
+	}
+
+	limits, _, err := client.RateLimit.Get(ctx)
+	if err != nil {
+		fmt.Printf("rate limit: FAIL: %v\n", err)
+		ok = false
+	} else {
+		fmt.Printf("rate limit: %d/%d core, %d/%d graphql remaining\n",
+			limits.GetCore().Remaining, limits.GetCore().Limit,
+			limits.GetGraphQL().Remaining, limits.GetGraphQL().Limit)
+	}
+
+	repos, err := fetchRepos(ctx, client, opts)
+	if err != nil {
+		fmt.Printf("repositories: FAIL: %v\n", err)
+		return false
+	}
+	fmt.Printf("repositories: %d\n", len(repos))
+
+	var sample *github.Repository
+	if i := slices.IndexFunc(repos, func(repo *github.Repository) bool { return !repo.GetArchived() }); i >= 0 {
+		sample = repos[i]
+	}
+
+	report := func(name string, err error) {
+		switch {
+		case err == nil:
+			fmt.Printf("collector %s: ok\n", name)
+		case permissionError(err):
+			fmt.Printf("collector %s: FAIL: permission denied: %v\n", name, err)
+			ok = false
+		default:
+			fmt.Printf("collector %s: FAIL: %v\n", name, err)
+			ok = false
+		}
+	}
+
+	report("repository", updateRepositoryMetrics(ctx, client, opts))
+	report("notifications", updateNotificationsMetrics(ctx, client, opts))
+	for _, c := range accountCollectors {
+		if opts.enabled(c.name) {
+			report(c.name, c.update(ctx, client, opts))
+		}
+	}
+	for _, c := range repoCollectors {
+		if !opts.enabled(c.name) {
+			continue
+		}
+		if sample == nil {
+			fmt.Printf("collector %s: skipped, no repositories\n", c.name)
+			continue
+		}
+		report(c.name, c.update(ctx, client, opts, sample))
+	}
+
+	return ok
+}
+
+// metricsRegistry remembers every registered collector so describe-metrics
+// can list metrics that have not been collected yet.
+type metricsRegistry struct {
+	*prometheus.Registry
+	collectors   []prometheus.Collector
+	descriptions map[prometheus.Collector]metricDescription
+}
+
+// MustRegister also records each collector's description, which must have
+// been created by one of the metric constructors below.
+func (r *metricsRegistry) MustRegister(cs ...prometheus.Collector) {
+	r.Registry.MustRegister(cs...)
+	for _, c := range cs {
+		d, ok := constructedMetrics[c]
+		if !ok {
+			panic(fmt.Sprintf("metric %v was not created with a metric constructor", c))
+		}
+		r.descriptions[c] = d
+	}
+	r.collectors = append(r.collectors, cs...)
+}
+
+type metricDescription struct {
+	name   string
+	help   string
+	labels []string
+}
+
+// constructedMetrics holds the options each metric was created with, since
+// client_golang offers no way to read them back from a collector.
+var constructedMetrics = make(map[prometheus.Collector]metricDescription)
+
+func newGaugeVec(opts prometheus.GaugeOpts, labels []string) *prometheus.GaugeVec {
+	vec := prometheus.NewGaugeVec(opts, labels)
+	constructedMetrics[vec] = metricDescription{name: prometheus.BuildFQName(opts.Namespace, opts.Subsystem, opts.Name), help: opts.Help, labels: labels}
+	return vec
+}
+
+func newGauge(opts prometheus.GaugeOpts) prometheus.Gauge {
+	gauge := prometheus.NewGauge(opts)
+	constructedMetrics[gauge] = metricDescription{name: prometheus.BuildFQName(opts.Namespace, opts.Subsystem, opts.Name), help: opts.Help}
+	return gauge
+}
+
+func newCounterVec(opts prometheus.CounterOpts, labels []string) *prometheus.CounterVec {
+	vec := prometheus.NewCounterVec(opts, labels)
+	constructedMetrics[vec] = metricDescription{name: prometheus.BuildFQName(opts.Namespace, opts.Subsystem, opts.Name), help: opts.Help, labels: labels}
+	return vec
+}
+
+func newHistogramVec(opts prometheus.HistogramOpts, labels []string) *prometheus.HistogramVec {
+	vec := prometheus.NewHistogramVec(opts, labels)
+	constructedMetrics[vec] = metricDescription{name: prometheus.BuildFQName(opts.Namespace, opts.Subsystem, opts.Name), help: opts.Help, labels: labels}
+	return vec
+}
+
+func describeMetrics(w io.Writer) {
+	var lines []string
+	for _, c := range registry.collectors {
+		d := registry.descriptions[c]
+		name := d.name
+		if len(d.labels) > 0 {
+			name += "{" + strings.Join(d.labels, ",") + "}"
+		}
+		lines = append(lines, fmt.Sprintf("%s\n    %s\n", name, d.help))
+	}
+
+	slices.Sort(lines)
+	for _, line := range lines {
+		fmt.Fprint(w, line)
+	}
+}
+
+// completionWords returns the subcommands and long flags of a go-arg struct,
+// read from its struct tags so completions follow new options automatically.
+func completionWords(t reflect.Type) (subcommands, flags []string) {
+	for _, field := range reflect.VisibleFields(t) {
+		// go-arg ignores fields tagged "-", such as config-only options.
+		tag, ok := field.Tag.Lookup("arg")
+		if !ok || tag == "-" {
+			continue
+		}
+
+		long := "--" + strings.ToLower(field.Name)
+		for _, part := range strings.Split(tag, ",") {
+			switch {
+			case part == "positional":
+				long = ""
+			case strings.HasPrefix(part, "subcommand:"):
+				subcommands = append(subcommands, strings.TrimPrefix(part, "subcommand:"))
+				_, subFlags := completionWords(field.Type.Elem())
+				flags = append(flags, subFlags...)
+				long = ""
+			case strings.HasPrefix(part, "--"):
+				long = part
+			}
+		}
+		if long != "" {
+			flags = append(flags, long)
+		}
+	}
+
+	slices.Sort(flags)
+	return subcommands, slices.Compact(flags)
+}
+
+func collectorNames() []string {
+	names := slices.Clone(repositoryQueryCollectors)
+	for _, c := range repoCollectors {
+		names = append(names, c.name)
+	}
+	for _, c := range accountCollectors {
+		names = append(names, c.name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+const bashCompletion = `_github_exporter() {
+	local cur="${COMP_WORDS[COMP_CWORD]}" prev="${COMP_WORDS[COMP_CWORD-1]}"
+	case "$prev" in
+	-c|--collector)
+		COMPREPLY=($(compgen -W "%[3]s" -- "$cur"))
+		return
+		;;
+	esac
+	COMPREPLY=($(compgen -W "%[1]s %[2]s" -- "$cur"))
+}
+complete -F _github_exporter github_exporter
+`
+
+func writeCompletion(w io.Writer, shell string) error {
+	subcommands, flags := completionWords(reflect.TypeFor[mainCommand]())
+	collectors := collectorNames()
+
+	switch shell {
+	case "bash":
+		_, err := fmt.Fprintf(w, bashCompletion, strings.Join(subcommands, " "), strings.Join(flags, " "), strings.Join(collectors, " "))
+		return err
+	case "zsh":
+		if _, err := fmt.Fprintln(w, "autoload -U +X bashcompinit && bashcompinit"); err != nil {
+			return err
+		}
+		_, err := fmt.Fprintf(w, bashCompletion, strings.Join(subcommands, " "), strings.Join(flags, " "), strings.Join(collectors, " "))
+		return err
+	case "fish":
+		var buf bytes.Buffer
+		fmt.Fprintf(&buf, "complete -c github_exporter -f -n __fish_use_subcommand -a '%s'\n", strings.Join(subcommands, " "))
+		for _, flag := range flags {
+			if flag == "--collector" {
+				fmt.Fprintf(&buf, "complete -c github_exporter -l collector -x -a '%s'\n", strings.Join(collectors, " "))
+				continue
+			}
+			fmt.Fprintf(&buf, "complete -c github_exporter -l %s\n", strings.TrimPrefix(flag, "--"))
+		}
+		_, err := buf.WriteTo(w)
+		return err
+	default:
+		return fmt.Errorf("unsupported shell %q", shell)
+	}
+}
+
+// healthcheck requests the readiness endpoint of a running exporter, so
+// container images do not need curl for their health checks.
+func healthcheck(baseURL string) error {
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get(strings.TrimSuffix(baseURL, "/") + "/ready")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("exporter not ready: %s", resp.Status)
+	}
+	return nil
+}
+
+type series struct {
+	labels map[string]string
+	value  float64
+}
+
+// gatherSeries returns the current value of every series of a gauge.
+func gatherSeries(reg prometheus.Gatherer, name string) ([]series, error) {
+	mfs, err := reg.Gather()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []series
+	for _, mf := range mfs {
+		if mf.GetName() != name {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			labels := make(map[string]string)
+			for _, lp := range m.GetLabel() {
+				labels[lp.GetName()] = lp.GetValue()
+			}
+			result = append(result, series{labels: labels, value: m.GetGauge().GetValue()})
+		}
+	}
+	return result, nil
+}
+
+// workflowsWithConclusion lists the workflows whose latest run concluded with
+// one of conclusions, which may also be given comma-separated.
+func workflowsWithConclusion(reg prometheus.Gatherer, conclusions []string) ([]string, error) {
+	var wanted []string
+	for _, c := range conclusions {
+		wanted = append(wanted, strings.Split(c, ",")...)
+	}
+
+	runs, err := gatherSeries(reg, "github_workflow_run_conclusion")
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []string
+	for _, run := range runs {
+		if conclusion := run.labels["github_workflow_run_conclusion"]; run.value == 1 && slices.Contains(wanted, conclusion) {
+			matches = append(matches, fmt.Sprintf("%s %q concluded %s", run.labels["github_repo"], run.labels["workflow_name"], conclusion))
+		}
+	}
+	slices.Sort(matches)
+	return matches, nil
+}
+
+type reportSection struct {
+	title string
+	items []string
+}
+
+// buildReport summarizes problems from the collected metrics. Security alerts
+// and releases are only reported when their collectors are enabled.
+func buildReport(reg prometheus.Gatherer, releaseAge time.Duration) ([]reportSection, error) {
+	failing, err := workflowsWithConclusion(reg, []string{"failure", "timed_out", "startup_failure"})
+	if err != nil {
+		return nil, err
+	}
 
-	g.Go(func() error {
-		repos, err := fetchUserRepos(ctx, client)
+	alerts := make(map[string]map[string]int)
+	addAlerts := func(name, kind string, include func(series) bool) error {
+		all, err := gatherSeries(reg, name)
 		if err != nil {
-			return fmt.Errorf("fetching repos: %w", err)
+			return err
 		}
-
-		repoGroup, ctx := errgroup.WithContext(ctx)
-
-		repoGroup.Go(func() error {
-			if err := updateRepoCountMetrics(ctx, repos); err != nil {
-				return fmt.Errorf("repo count metrics: %w", err)
-			}
-			return nil
-		})
-
-		for _, repo := range repos {
-			if repo.GetArchived() {
+		for _, s := range all {
+			if s.value == 0 || !include(s) {
 				continue
 			}
-			repoGroup.Go(func() error {
-				if err := updateWorkflowRunMetrics(ctx, client, repo); err != nil {
-					return fmt.Errorf("workflow metrics for %s: %w", repo.GetFullName(), err)
-				}
-				return nil
-			})
+			repo := s.labels["github_repo"]
+			if alerts[repo] == nil {
+				alerts[repo] = make(map[string]int)
+			}
+			alerts[repo][kind] += int(s.value)
 		}
-		return repoGroup.Wait()
-	})
+		return nil
+	}
+	if err := addAlerts("github_dependabot_alert_count", "Dependabot", func(series) bool { return true }); err != nil {
+		return nil, err
+	}
+	if err := addAlerts("github_secret_scanning_alert_count", "secret scanning", func(s series) bool { return s.labels["state"] == "open" }); err != nil {
+		return nil, err
+	}
+	var alertItems []string
+	for repo, kinds := range alerts {
+		var parts []string
+		for kind, count := range kinds {
+			parts = append(parts, fmt.Sprintf("%d %s", count, kind))
+		}
+		slices.Sort(parts)
+		alertItems = append(alertItems, fmt.Sprintf("%s: %s", repo, strings.Join(parts, ", ")))
+	}
+	slices.Sort(alertItems)
 
-	return g.Wait()
+	releases, err := gatherSeries(reg, "github_release_latest_age_days")
+	if err != nil {
+		return nil, err
+	}
+	var releaseItems []string
+	for _, release := range releases {
+		if release.value > releaseAge.Hours()/24 {
+			releaseItems = append(releaseItems, fmt.Sprintf("%s: last release %.0f days ago", release.labels["github_repo"], release.value))
+		}
+	}
+	slices.Sort(releaseItems)
+
+	return []reportSection{
+		{title: "Failing workflows", items: failing},
+		{title: "Open security alerts", items: alertItems},
+		{title: "Repositories without recent releases", items: releaseItems},
+	}, nil
 }
 
-const issuesGraphQLQuery = `
-query($login: String!) {
-	user(login: $login) {
-		repositories(first: 100, affiliations: OWNER, isArchived: false) {
-			nodes {
-				nameWithOwner
-				openIssues: issues(states: OPEN) { totalCount }
-				closedIssues: issues(states: CLOSED) { totalCount }
-				openPulls: pullRequests(states: OPEN) { totalCount }
-				closedPulls: pullRequests(states: CLOSED) { totalCount }
+func writeReport(w io.Writer, sections []reportSection, format string) error {
+	var buf bytes.Buffer
+	for _, section := range sections {
+		if len(section.items) == 0 {
+			continue
+		}
+		switch format {
+		case "markdown":
+			fmt.Fprintf(&buf, "## %s\n\n", section.title)
+			for _, item := range section.items {
+				fmt.Fprintf(&buf, "- %s\n", item)
 			}
+		case "text":
+			fmt.Fprintf(&buf, "%s:\n", section.title)
+			for _, item := range section.items {
+				fmt.Fprintf(&buf, "  %s\n", item)
+			}
+		default:
+			return fmt.Errorf("unsupported report format %q", format)
 		}
+		buf.WriteString("\n")
+	}
+	if buf.Len() == 0 {
+		buf.WriteString("No problems found.\n")
 	}
-}`
 
-type graphQLIssuesResponse struct {
-	Data struct {
-		User struct {
-			Repositories struct {
-				Nodes []struct {
-					NameWithOwner string `json:"nameWithOwner"`
-					OpenIssues    struct {
-						TotalCount int `json:"totalCount"`
-					} `json:"openIssues"`
-					ClosedIssues struct {
-						TotalCount int `json:"totalCount"`
-					} `json:"closedIssues"`
-					OpenPulls struct {
-						TotalCount int `json:"totalCount"`
-					} `json:"openPulls"`
-					ClosedPulls struct {
-						TotalCount int `json:"totalCount"`
-					} `json:"closedPulls"`
-				} `json:"nodes"`
-			} `json:"repositories"`
-		} `json:"user"`
-	} `json:"data"`
+	_, err := buf.WriteTo(w)
+	return err
 }
 
-func writeToStdout(reg *prometheus.Registry) error {
-	enc := expfmt.NewEncoder(os.Stdout, expfmt.NewFormat(expfmt.TypeTextPlain))
-	mfs, err := reg.Gather()
+// collectorCalls is roughly how many API calls a collector makes per
+// repository, or per account for account collectors. Collectors not listed
+// make one.
+var collectorCalls = map[string]int{
+	"fork_sync":           2,
+	"unreleased_commits":  2,
+	"webhooks":            2,
+	"security_features":   2,
+	"community":           2,
+	"dora":                2,
+	"discussions":         2,
+	"packages":            4,
+	"container_versions":  5,
+	"org_webhooks":        2,
+	"authored_pulls":      2,
+	"org_security_alerts": 3,
+	"copilot":             2,
+}
+
+// dryRun lists the repositories and estimates the API calls of one collection
+// cycle. Only listing the repositories uses the token's quota.
+func dryRun(ctx context.Context, client *githubClient, opts *collectorOptions) error {
+	repos, err := fetchRepos(ctx, client, opts)
 	if err != nil {
 		return err
 	}
-	for _, mf := range mfs {
-		if err := enc.Encode(mf); err != nil {
-			return err
+	active := slices.DeleteFunc(slices.Clone(repos), (*github.Repository).GetArchived)
+	refreshed := len(active)
+	if opts.ReposPerCycle > 0 {
+		refreshed = min(opts.ReposPerCycle, len(active))
+	}
+	accounts := 1 + len(opts.Orgs)
+
+	fmt.Printf("repositories: %d, %d active, %d refreshed per cycle\n", len(repos), len(active), refreshed)
+
+	total := 0
+	estimate := func(name string, calls int) {
+		fmt.Printf("%s: ~%d calls\n", name, calls)
+		total += calls
+	}
+	// Listings take at least one page; the repository query also looks up
+	// the user first and fetches at most 50 repositories per page.
+	pages := func(items, perPage int) int {
+		return max((items+perPage-1)/perPage, 1)
+	}
+	estimate("repository list", pages(len(repos), opts.PerPage))
+	estimate("repository query", pages(len(repos), min(opts.PerPage, 50))+1)
+	estimate("notifications", 1)
+	// Workflow definitions are cached, so steady state is one call per repo.
+	estimate("workflow runs", refreshed)
+	for _, name := range opts.Collectors {
+		calls := max(collectorCalls[name], 1)
+		switch {
+		case slices.Contains(repositoryQueryCollectors, name):
+			fmt.Printf("collector %s: no additional calls\n", name)
+		case slices.ContainsFunc(repoCollectors, func(c repoCollector) bool { return c.name == name }):
+			estimate("collector "+name, calls*refreshed)
+		default:
+			estimate("collector "+name, calls*accounts)
 		}
 	}
+	fmt.Printf("total: ~%d calls per cycle\n", total)
+
 	return nil
 }
 
-func activationListener() (net.Listener, error) {
-	if os.Getenv("LISTEN_PID") != fmt.Sprintf("%d", os.Getpid()) {
-		return nil, fmt.Errorf("expected LISTEN_PID=%d, but was %s", os.Getpid(), os.Getenv("LISTEN_PID"))
+// writeVersion prints the version and build details. Commit and BuildDate are
+// set with -ldflags, falling back to the VCS information Go embeds.
+func writeVersion(w io.Writer) {
+	commit, buildDate := Commit, BuildDate
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && commit == "":
+				commit = setting.Value
+			case setting.Key == "vcs.time" && buildDate == "":
+				buildDate = setting.Value
+			}
+		}
 	}
 
-	if os.Getenv("LISTEN_FDS") != "1" {
-		return nil, fmt.Errorf("expected LISTEN_FDS=1, but was %s", os.Getenv("LISTEN_FDS"))
-	}
+	fmt.Fprintf(w, "github_exporter %s\n", Version)
+	fmt.Fprintf(w, "commit: %s\n", cmp.Or(commit, "unknown"))
+	fmt.Fprintf(w, "build date: %s\n", cmp.Or(buildDate, "unknown"))
+	fmt.Fprintf(w, "go-github: %s\n", github.Version)
+	fmt.Fprintf(w, "go: %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+}
 
-	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
-	if len(names) != 1 {
-		return nil, fmt.Errorf("expected LISTEN_FDNAMES to set 1 name, but was '%s'", os.Getenv("LISTEN_FDNAMES"))
-	}
+// seriesValues flattens metric families into values keyed by the series in
+// exposition format, e.g. github_repo_stars{github_repo="a/b"}.
+func seriesValues(mfs []*dto.MetricFamily) map[string]float64 {
+	values := make(map[string]float64)
+	for _, mf := range mfs {
+		for _, m := range mf.GetMetric() {
+			labels := make([]string, 0, len(m.GetLabel()))
+			for _, lp := range m.GetLabel() {
+				labels = append(labels, fmt.Sprintf("%s=%q", lp.GetName(), lp.GetValue()))
+			}
+			slices.Sort(labels)
 
-	fd := 3
-	syscall.CloseOnExec(fd)
-	f := os.NewFile(uintptr(fd), names[0])
+			key := mf.GetName()
+			if len(labels) > 0 {
+				key += "{" + strings.Join(labels, ",") + "}"
+			}
 
-	ln, err := net.FileListener(f)
+			switch {
+			case m.Gauge != nil:
+				values[key] = m.GetGauge().GetValue()
+			case m.Counter != nil:
+				values[key] = m.GetCounter().GetValue()
+			case m.Untyped != nil:
+				values[key] = m.GetUntyped().GetValue()
+			}
+		}
+	}
+	return values
+}
+
+// readMetricsFile parses a text exposition file.
+func readMetricsFile(path string) (map[string]*dto.MetricFamily, error) {
+	f, err := os.Open(path)
 	if err != nil {
 		return nil, err
 	}
+	defer f.Close()
 
-	if err := f.Close(); err != nil {
-		return nil, fmt.Errorf("failed to close file: %w", err)
+	parser := expfmt.NewTextParser(model.UTF8Validation)
+	families, err := parser.TextToMetricFamilies(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
 	}
-
-	return ln, nil
+	return families, nil
 }
 
-func updateNotificationsMetrics(ctx context.Context, client *github.Client) error {
-	notifications, _, err := client.Activity.ListNotifications(ctx, nil)
+// writeMetricsDiff prints series added (+), removed (-) or changed (~) since
+// the previous metric families.
+func writeMetricsDiff(w io.Writer, previousFamilies map[string]*dto.MetricFamily, reg prometheus.Gatherer) error {
+	currentFamilies, err := reg.Gather()
 	if err != nil {
 		return err
 	}
 
-	unreadCount := 0
-	for _, notification := range notifications {
-		if notification.Unread != nil && *notification.Unread {
-			unreadCount++
+	previous := seriesValues(slices.Collect(maps.Values(previousFamilies)))
+	current := seriesValues(currentFamilies)
+
+	keys := slices.Sorted(maps.Keys(current))
+	for key := range previous {
+		if _, ok := current[key]; !ok {
+			keys = append(keys, key)
 		}
 	}
-	notificationCount.With(prometheus.Labels{"unread": "true"}).Set(float64(unreadCount))
+	slices.Sort(keys)
 
+	for _, key := range keys {
+		before, hadBefore := previous[key]
+		after, hasAfter := current[key]
+		switch {
+		case !hadBefore:
+			fmt.Fprintf(w, "+ %s %g\n", key, after)
+		case !hasAfter:
+			fmt.Fprintf(w, "- %s %g\n", key, before)
+		case before != after:
+			fmt.Fprintf(w, "~ %s %g -> %g\n", key, before, after)
+		}
+	}
 	return nil
 }
 
-func updateIssueMetrics(ctx context.Context, client *github.Client) error {
-	user, _, err := client.Users.Get(ctx, "")
+// restoreMetrics sets gauges from an exposition file saved by a previous run,
+// so /metrics is populated before the first collection cycle completes.
+func restoreMetrics(path string) error {
+	families, err := readMetricsFile(path)
 	if err != nil {
 		return err
 	}
-	username := user.GetLogin()
 
-	variables := map[string]any{
-		"login": username,
+	for _, c := range registry.collectors {
+		// The exporter's own state and the token's scopes and expiry describe
+		// this process, not GitHub, and are recomputed on the first cycle.
+		name := registry.descriptions[c].name
+		if strings.HasPrefix(name, "github_exporter_") || strings.HasPrefix(name, "github_token_") {
+			continue
+		}
+		mf, ok := families[name]
+		if !ok || mf.GetType() != dto.MetricType_GAUGE {
+			continue
+		}
+
+		for _, m := range mf.GetMetric() {
+			switch g := c.(type) {
+			case *prometheus.GaugeVec:
+				labels := make(prometheus.Labels)
+				for _, lp := range m.GetLabel() {
+					labels[lp.GetName()] = lp.GetValue()
+				}
+				if gauge, err := g.GetMetricWith(labels); err == nil {
+					gauge.Set(m.GetGauge().GetValue())
+				}
+			case prometheus.Gauge:
+				g.Set(m.GetGauge().GetValue())
+			}
+		}
 	}
+	return nil
+}
 
-	var response graphQLIssuesResponse
-	if err := executeGraphQL(client, ctx, issuesGraphQLQuery, variables, &response); err != nil {
-		return err
+// recordedResponse is a GitHub API response saved by --record.
+type recordedResponse struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+}
+
+// timestampPattern matches the dates and RFC 3339 timestamps collectors derive
+// from the current time, including URL-encoded ones in query strings.
+var timestampPattern = regexp.MustCompile(`\d{4}-\d{2}-\d{2}(T\d{2}(:|%3A)\d{2}(:|%3A)\d{2}(\.\d+)?(Z|[+-]\d{2}(:|%3A)\d{2})?)?`)
+
+// recordingPath names a recording after the request method, URL and body, so
+// GraphQL queries to the same endpoint are recorded separately. Dates and
+// timestamps are left out of the name, since windows such as "issues opened
+// in the last week" move with the clock and would otherwise never replay.
+func recordingPath(dir string, req *http.Request) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", req.Method, timestampPattern.ReplaceAllString(req.URL.String(), ""))
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			return "", err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		h.Write(timestampPattern.ReplaceAll(body, nil))
 	}
+	return filepath.Join(dir, hex.EncodeToString(h.Sum(nil))+".json"), nil
+}
 
-	for _, repo := range response.Data.User.Repositories.Nodes {
-		issueCount.With(prometheus.Labels{
-			"github_repo": repo.NameWithOwner,
-			"type":        "issue",
-			"state":       "open",
-		}).Set(float64(repo.OpenIssues.TotalCount))
+type recordingRoundTripper struct {
+	dir     string
+	wrapped http.RoundTripper
+}
 
-		issueCount.With(prometheus.Labels{
-			"github_repo": repo.NameWithOwner,
-			"type":        "issue",
-			"state":       "closed",
-		}).Set(float64(repo.ClosedIssues.TotalCount))
+func (r recordingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	path, err := recordingPath(r.dir, req)
+	if err != nil {
+		return nil, err
+	}
 
-		issueCount.With(prometheus.Labels{
-			"github_repo": repo.NameWithOwner,
-			"type":        "pull",
-			"state":       "open",
-		}).Set(float64(repo.OpenPulls.TotalCount))
+	resp, err := r.wrapped.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
 
-		issueCount.With(prometheus.Labels{
-			"github_repo": repo.NameWithOwner,
-			"type":        "pull",
-			"state":       "closed",
-		}).Set(float64(repo.ClosedPulls.TotalCount))
+	data, err := json.MarshalIndent(recordedResponse{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Status: resp.StatusCode,
+		Header: resp.Header,
+		Body:   body,
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(r.dir, 0o755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return nil, err
 	}
+	return resp, nil
+}
 
-	return nil
+type replayRoundTripper struct {
+	dir string
 }
 
-func fetchUserRepos(ctx context.Context, client *github.Client) ([]*github.Repository, error) {
-	opts := &github.RepositoryListByAuthenticatedUserOptions{
-		Type:      "owner",
-		Sort:      "full_name",
-		Direction: "asc",
-		ListOptions: github.ListOptions{
-			PerPage: 100,
-		},
+func (r replayRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	path, err := recordingPath(r.dir, req)
+	if err != nil {
+		return nil, err
 	}
 
-	var allRepos []*github.Repository
-	for {
-		repos, resp, err := client.Repositories.ListByAuthenticatedUser(ctx, opts)
-		if err != nil {
-			return nil, err
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("no recording for %s %s: %w", req.Method, req.URL, err)
+	}
+	var recorded recordedResponse
+	if err := json.Unmarshal(data, &recorded); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	return &http.Response{
+		Status:     fmt.Sprintf("%d %s", recorded.Status, http.StatusText(recorded.Status)),
+		StatusCode: recorded.Status,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     recorded.Header,
+		Body:       io.NopCloser(bytes.NewReader(recorded.Body)),
+		Request:    req,
+	}, nil
+}
+
+// demoLabelValues are the label values used by serve --demo. Labels not listed
+// are set to "example".
+var demoLabelValues = map[string][]string{
+	"github_repo":                    {"demo/website", "demo/api", "demo/cli"},
+	"parent_repo":                    {"upstream/api"},
+	"owner":                          {"demo"},
+	"user":                           {"demo"},
+	"org":                            {"demo-org"},
+	"enterprise":                     {"demo-enterprise"},
+	"visibility":                     {"public", "private"},
+	"archived":                       {"false", "true"},
+	"branch":                         {"main"},
+	"state":                          {"open", "closed"},
+	"type":                           {"issue", "pull"},
+	"severity":                       alertSeverities,
+	"package_type":                   packageTypes,
+	"status":                         {"operational", "degraded_performance"},
+	"workflow_name":                  {"CI", "Release"},
+	"github_workflow_run_conclusion": {"success", "failure"},
+	"quantile":                       {"0.5", "0.9"},
+	"window":                         {"1h", "24h"},
+	"unread":                         {"true"},
+	"tag_name":                       {"v1.2.0"},
+	"component":                      {"Actions", "API Requests", "Git Operations"},
+	"reason":                         {"mention", "review_requested", "ci_activity"},
+}
+
+// populateDemoMetrics fills every registered metric with plausible random
+// values, so dashboards can be previewed without a token.
+func populateDemoMetrics() {
+	for _, c := range registry.collectors {
+		// The scrape handler tracks its own in-flight count.
+		if c == scrapesInFlight {
+			continue
 		}
+		d := registry.descriptions[c]
 
-		for _, repo := range repos {
-			if repo != nil {
-				allRepos = append(allRepos, repo)
+		switch m := c.(type) {
+		case *prometheus.GaugeVec:
+			for i := range 3 {
+				labels := make(prometheus.Labels)
+				for _, name := range d.labels {
+					values := demoLabelValues[name]
+					if len(values) == 0 {
+						values = []string{"example"}
+					}
+					labels[name] = values[i%len(values)]
+				}
+				m.With(labels).Set(demoValue(d.name))
 			}
+		case prometheus.Gauge:
+			m.Set(demoValue(d.name))
 		}
+	}
+}
 
-		if resp.NextPage == 0 {
-			break
+func demoValue(name string) float64 {
+	const month = 30 * 24 * time.Hour
+	switch {
+	case strings.HasSuffix(name, "_timestamp_seconds"):
+		return float64(time.Now().Add(-rand.N(month)).Unix())
+	case strings.HasSuffix(name, "_seconds"):
+		return rand.N(month).Seconds()
+	case strings.HasSuffix(name, "_days"):
+		return float64(rand.IntN(90))
+	case strings.HasSuffix(name, "_percentage"):
+		return float64(rand.IntN(101))
+	case strings.HasSuffix(name, "_info"), strings.HasSuffix(name, "_scope"):
+		return 1
+	case strings.HasSuffix(name, "_enabled"), strings.HasSuffix(name, "_present"),
+		strings.HasSuffix(name, "_conclusion"), strings.HasSuffix(name, "_state"),
+		strings.HasSuffix(name, "_status"), strings.HasSuffix(name, "_skipped"):
+		return float64(rand.IntN(2))
+	default:
+		return float64(rand.IntN(50))
+	}
+}
+
+const deploymentsGraphQLQuery = `
+query($owner: String!, $name: String!, $environment: String!, $cursor: String, $perPage: Int!) {
+	repository(owner: $owner, name: $name) {
+		deployments(environments: [$environment], first: $perPage, after: $cursor, orderBy: {field: CREATED_AT, direction: DESC}) {
+			nodes {
+				createdAt
+				latestStatus { state }
+			}
+			pageInfo { hasNextPage endCursor }
 		}
-		opts.Page = resp.NextPage
 	}
+}`
 
-	return allRepos, nil
+type graphQLDeploymentsResponse struct {
+	Data struct {
+		Repository struct {
+			Deployments struct {
+				Nodes []struct {
+					CreatedAt    time.Time `json:"createdAt"`
+					LatestStatus *struct {
+						State string `json:"state"`
+					} `json:"latestStatus"`
+				} `json:"nodes"`
+				PageInfo graphQLPageInfo `json:"pageInfo"`
+			} `json:"deployments"`
+		} `json:"repository"`
+	} `json:"data"`
 }
 
-func updateRepoCountMetrics(ctx context.Context, repos []*github.Repository) error {
-	repoCounts := make(map[string]map[string]map[string]int)
+// updateDORAMetrics derives the four DORA metrics from deployments to
+// --dora-environment and recently merged pulls. Inactive deployments are
+// successful ones that have since been superseded.
+//
+// A deployment's failure comes from its status alone. Workflow conclusions
+// are not read: an Actions job deploying to an environment already sets the
+// deployment status from its result, and matching other runs to deployments
+// would be guesswork.
+func updateDORAMetrics(ctx context.Context, client *githubClient, opts *collectorOptions, repo *github.Repository) error {
+	since := time.Now().Add(-opts.DORAWindow)
 
-	for _, repo := range repos {
-		owner := repo.GetOwner().GetLogin()
+	variables := map[string]any{
+		"owner":       repo.GetOwner().GetLogin(),
+		"name":        repo.GetName(),
+		"environment": opts.DORAEnvironment,
+		"perPage":     opts.PerPage,
+	}
 
-		visibility := "public"
-		if repo.GetPrivate() {
-			visibility = "private"
+	type deployment struct {
+		createdAt time.Time
+		failed    bool
+	}
+	var deployments []deployment
+pages:
+	for {
+		var response graphQLDeploymentsResponse
+		if err := executeGraphQL(client, ctx, deploymentsGraphQLQuery, variables, &response); err != nil {
+			return err
 		}
 
-		archived := "false"
-		if repo.GetArchived() {
-			archived = "true"
+		nodes := response.Data.Repository.Deployments
+		for _, node := range nodes.Nodes {
+			if node.CreatedAt.Before(since) {
+				break pages
+			}
+			if node.LatestStatus == nil {
+				continue
+			}
+			switch node.LatestStatus.State {
+			case "SUCCESS", "INACTIVE":
+				deployments = append(deployments, deployment{createdAt: node.CreatedAt})
+			case "FAILURE", "ERROR":
+				deployments = append(deployments, deployment{createdAt: node.CreatedAt, failed: true})
+			}
 		}
 
-		if repoCounts[owner] == nil {
-			repoCounts[owner] = make(map[string]map[string]int)
-		}
-		if repoCounts[owner][visibility] == nil {
-			repoCounts[owner][visibility] = make(map[string]int)
+		if !nodes.PageInfo.HasNextPage {
+			break
 		}
+		variables["cursor"] = nodes.PageInfo.EndCursor
+	}
+	slices.Reverse(deployments)
 
-		repoCounts[owner][visibility][archived]++
+	fullName := repo.GetFullName()
+	labels := prometheus.Labels{"github_repo": fullName}
+	if len(deployments) == 0 {
+		doraDeploymentsPerDay.Delete(labels)
+		doraChangeFailureRate.Delete(labels)
+		setQuantiles(doraLeadTime, fullName, nil)
+		setQuantiles(doraTimeToRestore, fullName, nil)
+		return nil
 	}
 
-	for owner, visCounts := range repoCounts {
-		for visibility, archCounts := range visCounts {
-			for archived, count := range archCounts {
-				repoCount.With(prometheus.Labels{
-					"owner":      owner,
-					"visibility": visibility,
-					"archived":   archived,
-				}).Set(float64(count))
+	nextSuccess := func(after time.Time) (time.Time, bool) {
+		for _, d := range deployments {
+			if !d.failed && !d.createdAt.Before(after) {
+				return d.createdAt, true
 			}
 		}
+		return time.Time{}, false
 	}
 
-	return nil
-}
-
-type graphQLRequest struct {
-	Query     string         `json:"query"`
-	Variables map[string]any `json:"variables"`
-}
-
-func updateWorkflowRunMetrics(ctx context.Context, client *github.Client, repo *github.Repository) error {
-	owner, repoName := repo.GetOwner().GetLogin(), repo.GetName()
+	successes, failures := 0, 0
+	var restoreTimes []float64
+	for i, d := range deployments {
+		if !d.failed {
+			successes++
+			continue
+		}
+		failures++
+		if i > 0 && deployments[i-1].failed {
+			continue
+		}
+		if restored, ok := nextSuccess(d.createdAt); ok {
+			restoreTimes = append(restoreTimes, restored.Sub(d.createdAt).Seconds())
+		}
+	}
 
-	runs, _, err := client.Actions.ListRepositoryWorkflowRuns(ctx, owner, repoName, &github.ListWorkflowRunsOptions{
-		Branch: repo.GetDefaultBranch(),
-		Status: "completed",
-		ListOptions: github.ListOptions{
-			PerPage: 100,
-		},
-	})
+	pulls, err := cachedMergedPulls(ctx, client, opts, repo, since)
 	if err != nil {
 		return err
 	}
-
-	latestRuns := make(map[int64]*github.WorkflowRun)
-	for _, run := range runs.WorkflowRuns {
-		workflowID := run.GetWorkflowID()
-		if existing, ok := latestRuns[workflowID]; !ok || run.GetRunNumber() > existing.GetRunNumber() {
-			latestRuns[workflowID] = run
+	var leadTimes []float64
+	for _, pull := range pulls {
+		if deployed, ok := nextSuccess(pull.MergedAt); ok {
+			leadTimes = append(leadTimes, deployed.Sub(pull.MergedAt).Seconds())
 		}
 	}
 
-	workflows, _, err := client.Actions.ListWorkflows(ctx, owner, repoName, &github.ListOptions{})
-	if err != nil {
+	doraDeploymentsPerDay.With(labels).Set(float64(successes) / (opts.DORAWindow.Hours() / 24))
+	doraChangeFailureRate.With(labels).Set(float64(failures) / float64(successes+failures))
+	setQuantiles(doraLeadTime, fullName, leadTimes)
+	setQuantiles(doraTimeToRestore, fullName, restoreTimes)
+
+	return nil
+}
+
+func updateEnvironmentMetrics(ctx context.Context, client *githubClient, opts *collectorOptions, repo *github.Repository) error {
+	envs, resp, err := client.Repositories.ListEnvironments(ctx, repo.GetOwner().GetLogin(), repo.GetName(), &github.EnvironmentListOptions{
+		ListOptions: github.ListOptions{PerPage: 100},
+	})
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return nil
+	} else if err != nil {
 		return err
 	}
 
-	for _, workflow := range workflows.Workflows {
-		if latestRun, ok := latestRuns[workflow.GetID()]; ok {
-			workflowRunNumber.With(prometheus.Labels{
-				"github_repo":   *repo.FullName,
-				"workflow_name": workflow.GetName(),
-			}).Set(float64(latestRun.GetRunNumber()))
+	repoLabels := prometheus.Labels{"github_repo": repo.GetFullName()}
+	environmentRequiredReviewers.DeletePartialMatch(repoLabels)
+	environmentWaitTimerMinutes.DeletePartialMatch(repoLabels)
+	environmentInfo.DeletePartialMatch(repoLabels)
 
-			conclusions := []string{"action_required", "cancelled", "failure", "neutral",
-				"skipped", "stale", "startup_failure", "success", "timed_out"}
-			for _, conclusion := range conclusions {
-				value := 0.0
-				if conclusion == latestRun.GetConclusion() {
-					value = 1.0
-				}
-				workflowRunState.With(prometheus.Labels{
-					"github_repo":                    *repo.FullName,
-					"workflow_name":                  workflow.GetName(),
-					"github_workflow_run_conclusion": conclusion,
-				}).Set(value)
+	for _, env := range envs.Environments {
+		reviewers, waitTimer := 0, 0
+		for _, rule := range env.ProtectionRules {
+			switch rule.GetType() {
+			case "required_reviewers":
+				reviewers += len(rule.Reviewers)
+			case "wait_timer":
+				waitTimer = rule.GetWaitTimer()
+			}
+		}
+
+		branchPolicy := "none"
+		if policy := env.DeploymentBranchPolicy; policy != nil {
+			if policy.GetProtectedBranches() {
+				branchPolicy = "protected"
+			} else if policy.GetCustomBranchPolicies() {
+				branchPolicy = "custom"
 			}
 		}
+
+		labels := prometheus.Labels{
+			"github_repo": repo.GetFullName(),
+			"environment": env.GetName(),
+		}
+		environmentRequiredReviewers.With(labels).Set(float64(reviewers))
+		environmentWaitTimerMinutes.With(labels).Set(float64(waitTimer))
+		environmentInfo.With(prometheus.Labels{
+			"github_repo":   repo.GetFullName(),
+			"environment":   env.GetName(),
+			"branch_policy": branchPolicy,
+			"admins_bypass": strconv.FormatBool(env.GetCanAdminsBypass()),
+		}).Set(1)
 	}
 
 	return nil
 }
 
-func executeGraphQL(client *github.Client, ctx context.Context, query string, variables map[string]any, response any) error {
-	req := graphQLRequest{
-		Query:     query,
-		Variables: variables,
+// updateCheckRunMetrics covers third-party checks as well as Actions. Check
+// runs that have not completed are counted with the conclusion "pending".
+func updateCheckRunMetrics(ctx context.Context, client *githubClient, opts *collectorOptions, repo *github.Repository) error {
+	result, resp, err := client.Checks.ListCheckRunsForRef(ctx, repo.GetOwner().GetLogin(), repo.GetName(), repo.GetDefaultBranch(), &github.ListCheckRunsOptions{
+		Filter:      github.Ptr("latest"),
+		ListOptions: github.ListOptions{PerPage: 100},
+	})
+	if resp != nil && (resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusConflict) {
+		return nil
+	} else if err != nil {
+		return err
 	}
 
-	var buf bytes.Buffer
-	if err := json.NewEncoder(&buf).Encode(req); err != nil {
-		return err
+	counts := make(map[string]int)
+	var slowest *github.CheckRun
+	var slowestDuration time.Duration
+	for _, run := range result.CheckRuns {
+		if run.GetStatus() != "completed" {
+			counts["pending"]++
+			continue
+		}
+		counts[run.GetConclusion()]++
+
+		if duration := run.GetCompletedAt().Sub(run.GetStartedAt().Time); slowest == nil || duration > slowestDuration {
+			slowest, slowestDuration = run, duration
+		}
 	}
 
-	graphqlReq, err := http.NewRequestWithContext(ctx, "POST", "https://api.github.com/graphql", &buf)
-	if err != nil {
-		return err
+	repoLabels := prometheus.Labels{"github_repo": repo.GetFullName()}
+	checkRunCount.DeletePartialMatch(repoLabels)
+	for conclusion, count := range counts {
+		checkRunCount.With(prometheus.Labels{
+			"github_repo": repo.GetFullName(),
+			"conclusion":  conclusion,
+		}).Set(float64(count))
 	}
 
-	resp, err := client.Client().Do(graphqlReq)
-	if err != nil {
-		return err
+	checkRunSlowestDuration.DeletePartialMatch(repoLabels)
+	if slowest != nil {
+		checkRunSlowestDuration.With(prometheus.Labels{
+			"github_repo": repo.GetFullName(),
+			"check_name":  slowest.GetName(),
+		}).Set(slowestDuration.Seconds())
 	}
-	defer resp.Body.Close()
 
-	return json.NewDecoder(resp.Body).Decode(response)
+	return nil
 }